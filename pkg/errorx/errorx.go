@@ -42,3 +42,9 @@ func (o Errors) Error() string {
 	}
 	return o.Combine("", "; ").Error()
 }
+
+// Unwrap returns the errors, so that errors.Is and
+// errors.As can match any one of them.
+func (o Errors) Unwrap() []error {
+	return o
+}
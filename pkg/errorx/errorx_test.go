@@ -2,6 +2,7 @@ package errorx
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -82,3 +83,14 @@ func TestErrors_NilError(t *testing.T) {
 
 	assert.Equal(t, "WTF; Worked on my machine", errs.Error())
 }
+
+func TestErrors_Unwrap(t *testing.T) {
+	errNotFound := errors.New("not found")
+	var errs Errors
+
+	errs = append(errs, errors.New("WTF"))
+	errs = append(errs, fmt.Errorf("worked on my machine: %w", errNotFound))
+
+	var err error = errs
+	assert.ErrorIs(t, err, errNotFound)
+}
@@ -0,0 +1,148 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/PaesslerAG/gval"
+)
+
+// CustomOperator defines an operator that can be used in filters in
+// addition to the operators of filter.Operator; see RegisterOperator.
+// Either Func or Expression must be set.
+type CustomOperator struct {
+	// Func reports whether the value of a field matches the value
+	// of the operator. The value of the field is in the form that
+	// filters are evaluated against (e.g. a nested struct is a map
+	// keyed by the JSON names of its fields), and is nil if the field
+	// is null; the value of the operator is any JSON value, decoded
+	// as by the filter language (e.g. numbers are float64s, except
+	// for integers that a float64 can't represent exactly, which
+	// are int64s, or uint64s if they are too large for an int64).
+	Func func(field, value any) (bool, error)
+	// Expression renders the operator as an expression of the filter
+	// language, instead of as a call to Func, given the field and the
+	// value of the operator, both of which are already rendered.
+	Expression func(field, value string) string
+	// Language is an optional extension of the filter language, such
+	// as the functions that are used by the Expression.
+	Language gval.Language
+}
+
+// validOperatorName matches the names that
+// custom operators can be registered with.
+var validOperatorName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// customOperators contains the operators that have
+// been registered with RegisterOperator, by name.
+var customOperators = map[string]CustomOperator{}
+
+// RegisterOperator registers a custom operator with the given name, so
+// that it is accepted in the operator objects of filters, along with the
+// operators of filter.Operator; for example, after this registration:
+//
+//	filter.RegisterOperator("semver", filter.CustomOperator{
+//		Func: func(field, value any) (bool, error) {
+//			...
+//		},
+//	})
+//
+// this is a valid filter:
+//
+//	`{"version": {"semver": ">=1.2.0 <2.0.0"}}`
+//
+// The value of a custom operator can be any JSON value. The StructFilters
+// that are created after the registration accept the operator; to use it
+// in a hand-written filter struct, embed filter.Operator in a struct that
+// has a field for it:
+//
+//	type VersionOperator struct {
+//		filter.Operator
+//		Semver any `json:"semver,omitempty"`
+//	}
+//
+// Custom operators can't be translated into SQL. RegisterOperator must
+// be called before any filters are used, such as in an init function;
+// it panics if the name is not a valid operator name, or it is already
+// the name of an operator.
+func RegisterOperator(name string, op CustomOperator) {
+	err := RegisterOperatorE(name, op)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegisterOperatorE is the same as RegisterOperator, but returns
+// an error instead of panicking if the operator can't be registered.
+func RegisterOperatorE(name string, op CustomOperator) error {
+	if !validOperatorName.MatchString(name) {
+		return fmt.Errorf("%w: '%s' is not a valid name", ErrInvalidOperator, name)
+	}
+	if isReserved(name) || name == "and" || name == "or" || name == "not" {
+		return fmt.Errorf("%w: '%s' is already an operator", ErrInvalidOperator, name)
+	}
+	if op.Func == nil && op.Expression == nil {
+		return fmt.Errorf("%w: '%s' has neither a Func nor an Expression", ErrInvalidOperator, name)
+	}
+	expression := func(field string, value any) string {
+		return fmt.Sprintf("customOperator(%s, %s, %s)", strconv.Quote(name), field, literal(value))
+	}
+	if op.Expression != nil {
+		expression = func(field string, value any) string {
+			return op.Expression(field, literal(value))
+		}
+	}
+	operators[name] = operator{value: customValue, expression: expression}
+	customOperators[name] = op
+	language = gval.NewLanguage(language, op.Language)
+	customOperatorsChanged()
+
+	return nil
+}
+
+// customOperatorsChanged updates the type of the operator objects of
+// StructFilters, so that it has a field for each custom operator, and
+// clears the cached schemas and filter types, which depend on it.
+func customOperatorsChanged() {
+	typeOfOperator = reflect.TypeFor[*Operator]()
+	typeOfEmbeddedOperator = reflect.PointerTo(operatorFields)
+	if len(customOperators) > 0 {
+		fields := []reflect.StructField{{
+			Name:      "Operator",
+			Type:      operatorFields,
+			Anonymous: true,
+		}, operatorListField}
+		for _, name := range slices.Sorted(maps.Keys(customOperators)) {
+			fields = append(fields, reflect.StructField{
+				Name: "Op_" + name,
+				Type: reflect.TypeFor[any](),
+				Tag:  reflect.StructTag(fmt.Sprintf(`json:"%s,omitempty"`, name)),
+			})
+		}
+		typeOfOperator = reflect.PointerTo(reflect.StructOf(fields))
+		typeOfEmbeddedOperator = typeOfOperator
+	}
+	schemas.Clear()
+	filterTypes.Clear()
+}
+
+// customOperatorFunction is the gval function that evaluates the
+// custom operators that have a Func; its arguments are the name of
+// the operator, the value of the field, and the value of the operator.
+func customOperatorFunction(_ context.Context, args ...any) (any, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("customOperator() expects exactly three arguments")
+	}
+	name, _ := args[0].(string)
+	op, ok := customOperators[name]
+	if !ok || op.Func == nil {
+		return nil, fmt.Errorf("customOperator(): unknown operator '%v'", args[0])
+	}
+
+	return op.Func(args[1], args[2])
+}
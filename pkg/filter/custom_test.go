@@ -0,0 +1,150 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/PaesslerAG/gval"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registerTestOperator registers a custom operator for the duration
+// of a test, since the registry is global.
+func registerTestOperator(t *testing.T, name string, op CustomOperator) {
+	base := language
+	RegisterOperator(name, op)
+	t.Cleanup(func() {
+		delete(operators, name)
+		delete(customOperators, name)
+		language = base
+		customOperatorsChanged()
+	})
+}
+
+func divisibleBy(field, value any) (bool, error) {
+	n, ok := toFloat(field)
+	if !ok {
+		return false, nil
+	}
+	d, ok := value.(float64)
+	if !ok || d == 0 {
+		return false, fmt.Errorf("divisibleBy requires a non-zero number")
+	}
+	return math.Mod(n, d) == 0, nil
+}
+
+func TestRegisterOperator_Func(t *testing.T) {
+	registerTestOperator(t, "divisibleBy", CustomOperator{Func: divisibleBy})
+
+	structFilter := NewStructFilter[Movie](`{"movieYear": {"divisibleBy": 5}}`)
+	result := FilterAllFor(structFilter, testMovieList)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, 1985, result[0].MovieYear)
+	assert.Equal(t, `customOperator("divisibleBy", $["movieYear"], 5)`, GetExpression(structFilter.Any))
+}
+
+func TestRegisterOperator_Expression(t *testing.T) {
+	registerTestOperator(t, "inDecade", CustomOperator{
+		Expression: func(field, value string) string {
+			return fmt.Sprintf("decadeOf(%s) == %s", field, value)
+		},
+		Language: gval.Function("decadeOf", func(_ context.Context, args ...any) (any, error) {
+			year, _ := toFloat(args[0])
+			return math.Floor(year/10) * 10, nil
+		}),
+	})
+
+	structFilter := NewStructFilter[Movie](`{"movieYear": {"inDecade": 1990}}`)
+	result := FilterAllFor(structFilter, testMovieList)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, 1994, result[0].MovieYear)
+}
+
+func TestRegisterOperator_HandWrittenFilter(t *testing.T) {
+	registerTestOperator(t, "divisibleBy", CustomOperator{Func: divisibleBy})
+	type yearOperator struct {
+		Operator
+		DivisibleBy any `json:"divisibleBy,omitempty"`
+	}
+	type movieFilter struct {
+		MovieYear *yearOperator `json:"movieYear,omitempty"`
+	}
+	filter := movieFilter{MovieYear: &yearOperator{Operator: Operator{Gte: 1980}, DivisibleBy: 2}}
+
+	result, err := ShouldIncludeE(filter, testMovieList[1])
+
+	require.NoError(t, err)
+	assert.True(t, result)
+	_, _, err = ToSQL[Movie](filter, Postgres)
+	assert.ErrorIs(t, err, ErrUnsupportedOperator)
+}
+
+func TestRegisterOperator_JSONValue(t *testing.T) {
+	registerTestOperator(t, "nearby", CustomOperator{Func: func(field, value any) (bool, error) {
+		location, _ := field.(map[string]any)
+		center, _ := value.(map[string]any)
+		if location == nil || center == nil {
+			return false, nil
+		}
+		lat, _ := toFloat(location["lat"])
+		lng, _ := toFloat(location["lng"])
+		distance := math.Hypot(lat-center["lat"].(float64), lng-center["lng"].(float64))
+		return distance <= center["radius"].(float64), nil
+	}})
+
+	mapFilter := NewMapFilter(`{"location": {"nearby": {"lat": 51.5, "lng": -0.1, "radius": 1}}}`)
+
+	assert.True(t, mapFilter.ShouldInclude(map[string]any{"location": map[string]any{"lat": 51.6, "lng": 0.2}}))
+	assert.False(t, mapFilter.ShouldInclude(map[string]any{"location": map[string]any{"lat": 48.9, "lng": 2.4}}))
+	assert.False(t, mapFilter.ShouldInclude(map[string]any{}))
+}
+
+func TestRegisterOperator_TextAndSchema(t *testing.T) {
+	registerTestOperator(t, "divisibleBy", CustomOperator{Func: divisibleBy})
+
+	filterJson, err := ParseText(`movieYear divisibleBy 5 or title ~ "Shawshank"`)
+	require.NoError(t, err)
+	assert.Len(t, FilterAllFor(NewStructFilter[Movie](filterJson), testMovieList), 2)
+
+	schema := JSONSchema[Movie]()
+	properties := schema["$defs"].(map[string]any)["IntegerOperator"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{}, properties["divisibleBy"])
+}
+
+func TestRegisterOperatorE_Errors(t *testing.T) {
+	registerTestOperator(t, "divisibleBy", CustomOperator{Func: divisibleBy})
+
+	tests := []struct {
+		name   string
+		opName string
+		op     CustomOperator
+	}{
+		{"built-in operator", "eq", CustomOperator{Func: divisibleBy}},
+		{"quantifier", "any", CustomOperator{Func: divisibleBy}},
+		{"logical operator", "or", CustomOperator{Func: divisibleBy}},
+		{"already registered", "divisibleBy", CustomOperator{Func: divisibleBy}},
+		{"invalid name", "divisible-by", CustomOperator{Func: divisibleBy}},
+		{"no definition", "multipleOf", CustomOperator{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RegisterOperatorE(tt.opName, tt.op)
+
+			assert.ErrorIs(t, err, ErrInvalidOperator)
+		})
+	}
+}
+
+func TestRegisterOperator_InvalidValue(t *testing.T) {
+	registerTestOperator(t, "divisibleBy", CustomOperator{Func: divisibleBy})
+
+	structFilter := NewStructFilter[Movie](`{"movieYear": {"divisibleBy": "five"}}`)
+	_, err := FilterAllForE(structFilter, testMovieList)
+
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
@@ -1,5 +1,12 @@
 package filter
 
+import (
+	"time"
+
+	"github.com/tartale/go/pkg/jsontime"
+	"github.com/tartale/go/pkg/primitives"
+)
+
 type ShowKind string
 
 const (
@@ -8,10 +15,27 @@ const (
 )
 
 type Movie struct {
-	Kind        ShowKind `json:"kind,omitempty"`
-	Title       string   `json:"title,omitempty"`
-	Description string   `json:"description,omitempty"`
-	MovieYear   int      `json:"movieYear,omitempty"`
+	Kind        ShowKind      `json:"kind,omitempty"`
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	MovieYear   int           `json:"movieYear,omitempty" db:"movie_year"`
+	Tagline     *string       `json:"tagline,omitempty"`
+	Director    *Person       `json:"director,omitempty"`
+	Studio      Studio        `json:"studio,omitempty"`
+	Genres      []string      `json:"genres,omitempty"`
+	Cast        []Person      `json:"cast,omitempty"`
+	ReleaseDate jsontime.Time `json:"releaseDate,omitempty" format:"2006-01-02" db:"release_date"`
+	UpdatedAt   time.Time     `json:"updatedAt,omitempty"`
+}
+
+type Person struct {
+	Name      string `json:"name,omitempty"`
+	BirthYear int    `json:"birthYear,omitempty" db:"birth_year"`
+}
+
+type Studio struct {
+	Name    string `json:"name,omitempty"`
+	Country string `json:"country,omitempty"`
 }
 
 type MovieFilter struct {
@@ -19,8 +43,26 @@ type MovieFilter struct {
 	Title       *Operator      `json:"title,omitempty"`
 	Description *Operator      `json:"description,omitempty"`
 	MovieYear   *Operator      `json:"movieYear,omitempty"`
+	Tagline     *Operator      `json:"tagline,omitempty"`
+	Director    *PersonFilter  `json:"director,omitempty"`
+	Studio      *StudioFilter  `json:"studio,omitempty"`
+	Genres      *Operator      `json:"genres,omitempty"`
+	Cast        *Operator      `json:"cast,omitempty"`
+	ReleaseDate *Operator      `json:"releaseDate,omitempty"`
+	UpdatedAt   *Operator      `json:"updatedAt,omitempty"`
 	And         []*MovieFilter `json:"and,omitempty"`
 	Or          []*MovieFilter `json:"or,omitempty"`
+	Not         *MovieFilter   `json:"not,omitempty"`
+}
+
+type PersonFilter struct {
+	Name      *Operator `json:"name,omitempty"`
+	BirthYear *Operator `json:"birthYear,omitempty"`
+}
+
+type StudioFilter struct {
+	Name    *Operator `json:"name,omitempty"`
+	Country *Operator `json:"country,omitempty"`
 }
 
 var testMovie = Movie{
@@ -36,11 +78,30 @@ var testMovieList = []Movie{
 		Title:       "Back to the Future",
 		Description: "The time travel adventures of Doc Brown and Marty McFly",
 		MovieYear:   1985,
+		Tagline:     primitives.Ref("He's the only kid ever to get into trouble before he was born."),
+		Director:    &Person{Name: "Robert Zemeckis", BirthYear: 1952},
+		Studio:      Studio{Name: "Universal Pictures", Country: "US"},
+		Genres:      []string{"Adventure", "Comedy", "Sci-Fi"},
+		Cast: []Person{
+			{Name: "Michael J. Fox", BirthYear: 1961},
+			{Name: "Christopher Lloyd", BirthYear: 1938},
+			{Name: "Lea Thompson", BirthYear: 1961},
+		},
+		ReleaseDate: *jsontime.New(time.Date(1985, time.July, 3, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:   time.Now().Add(-24 * time.Hour),
 	},
 	{
 		Kind:        MOVIE,
 		Title:       "The Shawshank Redemption",
 		Description: "Andy Dufresne goes to prison with Morgan Freeman.",
 		MovieYear:   1994,
+		Studio:      Studio{Name: "Castle Rock Entertainment", Country: "US"},
+		Genres:      []string{"Drama"},
+		Cast: []Person{
+			{Name: "Tim Robbins", BirthYear: 1958},
+			{Name: "Morgan Freeman", BirthYear: 1937},
+		},
+		ReleaseDate: *jsontime.New(time.Date(1994, time.September, 23, 0, 0, 0, 0, time.UTC)),
+		UpdatedAt:   time.Now().Add(-30 * 24 * time.Hour),
 	},
 }
@@ -0,0 +1,58 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/tartale/go/pkg/errorz"
+)
+
+// ErrInvalidFilter is returned when a filter is not
+// well-formed (e.g. malformed JSON, or an unexpected
+// value where a filter object was expected).
+var ErrInvalidFilter = fmt.Errorf("%w: invalid filter", errorz.ErrBadRequest)
+
+// ErrUnknownField is returned when a filter refers to
+// a field that does not exist in the filtered type.
+var ErrUnknownField = fmt.Errorf("%w: unknown field", errorz.ErrBadRequest)
+
+// ErrInvalidOperator is returned when a filter uses an
+// operator that is not defined in filter.Operator.
+var ErrInvalidOperator = fmt.Errorf("%w: invalid operator", errorz.ErrBadRequest)
+
+// ErrTypeMismatch is returned when the value given to an
+// operator can't be compared with the value of the field.
+var ErrTypeMismatch = fmt.Errorf("%w: type mismatch", errorz.ErrBadRequest)
+
+// ErrInvalidPattern is returned when the regular expression
+// of a "matches" or "imatches" operator does not compile; it
+// is a kind of ErrInvalidFilter.
+var ErrInvalidPattern = fmt.Errorf("%w: invalid pattern", ErrInvalidFilter)
+
+// ErrOperatorNotAllowed is returned when a filter applies an
+// operator to a field whose filter tag doesn't allow it (e.g.
+// `filter:"eq,in"`); it is a kind of ErrInvalidOperator.
+var ErrOperatorNotAllowed = fmt.Errorf("%w: operator not allowed", ErrInvalidOperator)
+
+// ErrFilterTooComplex is returned when a filter exceeds
+// one of the limits of DefaultLimits (e.g. MaxDepth).
+var ErrFilterTooComplex = fmt.Errorf("%w: filter too complex", errorz.ErrBadRequest)
+
+// ErrUnsupportedOperator is returned when a filter uses an
+// operator that can't be translated (e.g. into SQL; see ToSQL).
+var ErrUnsupportedOperator = fmt.Errorf("%w: unsupported operator", errorz.ErrBadRequest)
+
+// PathError records an error in a filter, along with the
+// JSON path to the part of the filter that caused it
+// (e.g. "$[1].or[0].title.eq").
+type PathError struct {
+	Path string
+	Err  error
+}
+
+func (e *PathError) Error() string {
+	return fmt.Sprintf("%s at '%s'", e.Err, e.Path)
+}
+
+func (e *PathError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,166 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Explanation is a node in the trace of the evaluation of a filter
+// against a value, which shows why the value passed the filter or not.
+// The Clause is "and", "or", "not", "comparison", or the name of a
+// quantifier (e.g. "any"). The nodes of comparisons have the Field
+// that was compared (with the index of the element within a quantifier,
+// e.g. "cast[1].name"), its Value, and the Operator and Literal that
+// it was compared with; the nodes of quantifiers have the list as their
+// Value, and the explanation of each element as their Children. Every
+// node has the Result of its clause, or the Error that prevented it
+// from being evaluated.
+type Explanation struct {
+	Clause    string        `json:"clause"`
+	Field     string        `json:"field,omitempty"`
+	Operator  string        `json:"operator,omitempty"`
+	Literal   any           `json:"literal,omitempty"`
+	Normalize string        `json:"normalize,omitempty"`
+	Value     any           `json:"value"`
+	Result    bool          `json:"result"`
+	Error     string        `json:"error,omitempty"`
+	Children  []Explanation `json:"children,omitempty"`
+}
+
+// MarshalJSON omits the value of the nodes that don't have a
+// field, but keeps the null value of a field that is null.
+func (e Explanation) MarshalJSON() ([]byte, error) {
+	var value *any
+	if e.Field != "" {
+		value = &e.Value
+	}
+	return json.Marshal(struct {
+		Clause    string        `json:"clause"`
+		Field     string        `json:"field,omitempty"`
+		Operator  string        `json:"operator,omitempty"`
+		Literal   any           `json:"literal,omitempty"`
+		Normalize string        `json:"normalize,omitempty"`
+		Value     *any          `json:"value,omitempty"`
+		Result    bool          `json:"result"`
+		Error     string        `json:"error,omitempty"`
+		Children  []Explanation `json:"children,omitempty"`
+	}{e.Clause, e.Field, e.Operator, e.Literal, e.Normalize, value, e.Result, e.Error, e.Children})
+}
+
+// Explain evaluates the StructFilter against the given object, and
+// returns a trace of the evaluation; unlike ShouldInclude, every clause
+// is evaluated, so that the trace is complete. The Result of the root
+// of the trace is the same as the result of ShouldInclude. It panics if
+// the filter is not valid.
+func (sf StructFilter[T]) Explain(val T) Explanation {
+	explanation, err := sf.ExplainE(val)
+	if err != nil {
+		panic(err)
+	}
+
+	return explanation
+}
+
+// ExplainE is the same as Explain, but returns an error instead
+// of panicking if the filter is not valid. Errors that occur while
+// evaluating the filter are reported in the nodes of the trace.
+func (sf StructFilter[T]) ExplainE(val T) (Explanation, error) {
+	expr, err := sf.expr()
+	if err != nil {
+		return Explanation{}, err
+	}
+	values := valueOf(reflect.ValueOf(val))
+
+	return explain(expr, values, func(field string) string { return field }), nil
+}
+
+// explain evaluates the given expression tree against the given
+// values; the rename function converts the fields of comparisons
+// into the names that they are reported with.
+func explain(expr Expr, values any, rename func(string) string) Explanation {
+	switch e := expr.(type) {
+	case And:
+		explanation := Explanation{Clause: "and", Result: true}
+		for _, clause := range e {
+			child := explain(clause, values, rename)
+			explanation.Result = explanation.Result && child.Result
+			explanation.Children = append(explanation.Children, child)
+		}
+		return explanation
+	case Or:
+		explanation := Explanation{Clause: "or"}
+		for _, clause := range e {
+			child := explain(clause, values, rename)
+			explanation.Result = explanation.Result || child.Result
+			explanation.Children = append(explanation.Children, child)
+		}
+		return explanation
+	case Not:
+		child := explain(e.Expr, values, rename)
+		return Explanation{Clause: "not", Result: !child.Result && child.Error == "", Children: []Explanation{child}}
+	case Comparison:
+		explanation := Explanation{
+			Clause:    "comparison",
+			Field:     rename(e.Field),
+			Operator:  e.Operator,
+			Literal:   e.Value,
+			Normalize: e.Normalize,
+			Value:     selectPath(values, strings.Split(e.Field, ".")),
+		}
+		explanation.Result, explanation.Error = evaluate(e, values)
+		return explanation
+	case Quantifier:
+		field := rename(e.Field)
+		list := selectPath(values, strings.Split(e.Field, "."))
+		explanation := Explanation{Clause: e.Quantifier, Field: field, Value: list}
+		explanation.Result, explanation.Error = evaluate(e, values)
+		elems, _ := toList(list)
+		if e.Quantifier == "size" {
+			elemName := fmt.Sprintf("size(%s)", field)
+			child := explain(e.Expr, map[string]any{elementVariable: len(elems)}, elementRename(elemName))
+			explanation.Children = append(explanation.Children, child)
+			return explanation
+		}
+		for i, elem := range elems {
+			elemName := fmt.Sprintf("%s[%d]", field, i)
+			child := explain(e.Expr, map[string]any{elementVariable: elem}, elementRename(elemName))
+			explanation.Children = append(explanation.Children, child)
+		}
+		return explanation
+	}
+
+	return Explanation{Clause: fmt.Sprintf("%T", expr), Error: "unknown clause"}
+}
+
+// evaluate evaluates a single expression against the
+// given values, in the same way as ShouldInclude.
+func evaluate(expr Expr, values any) (bool, string) {
+	evaluable, err := Compile(expr)
+	if err != nil {
+		return false, err.Error()
+	}
+	result, err := evaluable.EvalBool(context.Background(), values)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	return result, ""
+}
+
+// elementRename returns a function that renames the fields of the
+// sub-expression of a quantifier, which refer to the element as
+// "elem", after the given name of the element (e.g. "cast[1]").
+func elementRename(elemName string) func(string) string {
+	return func(field string) string {
+		if field == elementVariable {
+			return elemName
+		}
+		if rest, ok := strings.CutPrefix(field, elementVariable+"."); ok {
+			return elemName + "." + rest
+		}
+		return field
+	}
+}
@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStructFilter_Explain(t *testing.T) {
+	structFilter := NewStructFilter[Movie](`{"or": [
+		{"title": {"startsWith": "Back"}, "movieYear": {"gt": 1990}},
+		{"cast": {"any": {"name": {"eq": "Christopher Lloyd"}}, "size": {"gte": 3}}, "not": {"director.name": {"exists": true}}}
+	]}`)
+
+	explanation := structFilter.Explain(testMovieList[0])
+
+	explanationJson, err := json.Marshal(explanation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"clause": "or",
+		"result": false,
+		"children": [
+			{
+				"clause": "and",
+				"result": false,
+				"children": [
+					{"clause": "comparison", "field": "title", "operator": "startsWith", "literal": "Back", "value": "Back to the Future", "result": true},
+					{"clause": "comparison", "field": "movieYear", "operator": "gt", "literal": 1990, "value": 1985, "result": false}
+				]
+			},
+			{
+				"clause": "and",
+				"result": false,
+				"children": [
+					{
+						"clause": "and",
+						"result": true,
+						"children": [
+							{
+								"clause": "any",
+								"field": "cast",
+								"value": [
+									{"name": "Michael J. Fox", "birthYear": 1961},
+									{"name": "Christopher Lloyd", "birthYear": 1938},
+									{"name": "Lea Thompson", "birthYear": 1961}
+								],
+								"result": true,
+								"children": [
+									{"clause": "comparison", "field": "cast[0].name", "operator": "eq", "literal": "Christopher Lloyd", "value": "Michael J. Fox", "result": false},
+									{"clause": "comparison", "field": "cast[1].name", "operator": "eq", "literal": "Christopher Lloyd", "value": "Christopher Lloyd", "result": true},
+									{"clause": "comparison", "field": "cast[2].name", "operator": "eq", "literal": "Christopher Lloyd", "value": "Lea Thompson", "result": false}
+								]
+							},
+							{
+								"clause": "size",
+								"field": "cast",
+								"value": [
+									{"name": "Michael J. Fox", "birthYear": 1961},
+									{"name": "Christopher Lloyd", "birthYear": 1938},
+									{"name": "Lea Thompson", "birthYear": 1961}
+								],
+								"result": true,
+								"children": [
+									{"clause": "comparison", "field": "size(cast)", "operator": "gte", "literal": 3, "value": 3, "result": true}
+								]
+							}
+						]
+					},
+					{
+						"clause": "not",
+						"result": false,
+						"children": [
+							{"clause": "comparison", "field": "director.name", "operator": "exists", "literal": true, "value": "Robert Zemeckis", "result": true}
+						]
+					}
+				]
+			}
+		]
+	}`, string(explanationJson))
+}
+
+func TestStructFilter_Explain_MatchesShouldInclude(t *testing.T) {
+	filterJsons := []string{
+		`[{"kind": {"eq": "MOVIE"}}]`,
+		`[{"tagline": {"isNull": true}}, {"or": [{"genres": {"all": {"eq": "Drama"}}}]}]`,
+		`{"not": {"releaseDate": {"before": "1990-01-01"}}, "studio.country": {"in": ["US"]}}`,
+		`[{"cast": {"none": {"birthYear": {"lt": 1940}}}}]`,
+		`[]`,
+	}
+
+	for _, filterJson := range filterJsons {
+		structFilter := NewStructFilter[Movie](filterJson)
+		for _, movie := range testMovieList {
+			assert.Equal(t, structFilter.ShouldInclude(movie), structFilter.Explain(movie).Result, "%s: %s", filterJson, movie.Title)
+		}
+	}
+}
+
+func TestStructFilter_Explain_NullField(t *testing.T) {
+	structFilter := NewStructFilter[Movie](`{"director.name": {"eq": "Frank Darabont"}}`)
+
+	explanation := structFilter.Explain(Movie{Title: "Unknown"})
+
+	explanationJson, err := json.Marshal(explanation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"clause": "comparison", "field": "director.name", "operator": "eq", "literal": "Frank Darabont", "value": null, "result": false}`, string(explanationJson))
+}
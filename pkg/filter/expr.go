@@ -0,0 +1,928 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tartale/go/pkg/errorx"
+)
+
+// validListIndex matches the segments of the path of a field
+// that select an element of a list (e.g. "cast.0.name"); see
+// (*parser).field.
+var validListIndex = regexp.MustCompile(`^[0-9]+$`)
+
+// Expr is a node in the expression tree that the JSON
+// representation of a filter is decoded into. Because the
+// tree is built from decoded JSON tokens, the values supplied
+// by a client are always rendered as properly-escaped literals,
+// and can never change the structure of the expression.
+type Expr interface {
+	// Expression returns the gval-compatible boolean
+	// expression that is equivalent to the node.
+	Expression() string
+}
+
+// And is an expression that is true if all
+// of its sub-expressions are true.
+type And []Expr
+
+// Or is an expression that is true if any
+// of its sub-expressions are true.
+type Or []Expr
+
+// Not is an expression that is true if its
+// sub-expression is false.
+type Not struct {
+	Expr Expr
+}
+
+// Comparison is an expression that compares the value
+// of a single field against a literal, using one of the
+// operators of filter.Operator. The Field is the JSON name
+// of the field, or a dotted path to a field of a nested
+// struct (e.g. "director.name"). If Normalize is set, it is
+// the Unicode normalization form (e.g. "NFC") that is applied
+// to both the field and the literal before they are compared.
+type Comparison struct {
+	Field     string
+	Operator  string
+	Value     any
+	Normalize string
+}
+
+// Quantifier is an expression that applies a sub-expression
+// to the elements of a list field: "any", "all" and "none" are
+// true if the sub-expression is true for at least one, all, or
+// none of the elements, respectively, and "size" applies the
+// sub-expression to the number of elements. Within the
+// sub-expression, the element (or the size) is referred to
+// by the "elem" variable (e.g. `$["elem"]["name"] == "Doc Brown"`).
+type Quantifier struct {
+	Field      string
+	Quantifier string
+	Expr       Expr
+}
+
+// elementVariable is the name of the variable that refers
+// to the element in the sub-expression of a Quantifier.
+const elementVariable = "elem"
+
+// Expression implements the Expr interface. An empty
+// And is always true.
+func (a And) Expression() string {
+	if len(a) == 0 {
+		return "true"
+	}
+	return joinExpressions(a, " && ")
+}
+
+// Expression implements the Expr interface. An empty
+// Or is always false.
+func (o Or) Expression() string {
+	if len(o) == 0 {
+		return "false"
+	}
+	return joinExpressions(o, " || ")
+}
+
+// Expression implements the Expr interface.
+func (n Not) Expression() string {
+	return fmt.Sprintf("!(%s)", n.Expr.Expression())
+}
+
+// Expression implements the Expr interface.
+func (c Comparison) Expression() string {
+	op, ok := operators[c.Operator]
+	if !ok {
+		// An unknown operator is rendered as-is, so that
+		// compiling the expression fails.
+		return fmt.Sprintf("%s %s %s", variable(c.Field), c.Operator, literal(c.Value))
+	}
+	field, value := variable(c.Field), c.Value
+	if c.Normalize != "" {
+		field = fmt.Sprintf("normalize(%s, %s)", field, strconv.Quote(c.Normalize))
+		value = mapStrings(value, func(s string) string { return normalize(s, c.Normalize) })
+	}
+	if op.foldCase {
+		field = fmt.Sprintf("fold(%s)", field)
+		value = mapStrings(value, caseFold)
+	}
+
+	return op.expression(field, value)
+}
+
+// Expression implements the Expr interface. The sub-expression
+// is rendered as a string literal, which the function of the
+// quantifier compiles and evaluates against each element.
+func (q Quantifier) Expression() string {
+	function, ok := quantifiers[q.Quantifier]
+	if !ok {
+		function = q.Quantifier
+	}
+	return fmt.Sprintf("%s(%s, %s)", function, variable(q.Field), strconv.Quote(q.Expr.Expression()))
+}
+
+// variable renders the (dotted) path of a field as a variable
+// of the filter language, with its segments as selectors of the
+// "$" root (e.g. `$["cast"][0]["name"]`); the names of fields are
+// quoted, so they can have any characters, and are never taken
+// for the keywords or functions of the language (e.g. "true").
+func variable(path string) string {
+	var v strings.Builder
+	v.WriteString("$")
+	for _, segment := range strings.Split(path, ".") {
+		if validListIndex.MatchString(segment) {
+			fmt.Fprintf(&v, "[%s]", segment)
+		} else {
+			fmt.Fprintf(&v, "[%s]", strconv.Quote(segment))
+		}
+	}
+
+	return v.String()
+}
+
+// ParseExpr decodes the JSON representation of a filter
+// into an expression tree. The JSON can either be a single
+// filter object, or a list of filter objects.
+//
+// The keys of a filter object, and the elements of a list of
+// filter objects, are AND'ed together, regardless of their order.
+// The value of an "and" clause is a list of filter objects that
+// are AND'ed together, the value of an "or" clause is a list of
+// filter objects that are OR'ed together, and the value of a "not"
+// clause is a filter object that is negated. For example:
+//
+//	`{"movieYear": {"gte": 1985}, "or": [{"title": {"matches": "Back to the .*"}}, {"not": {"kind": {"eq": "MOVIE"}}}]}`
+//
+// is equivalent to:
+//
+//	`$["movieYear"] >= 1985 && ($["title"] =~ "Back to the .*" || !($["kind"] == "MOVIE"))`
+//
+// Any error returned is a *PathError that wraps one of the
+// errors of this package (e.g. ErrInvalidOperator). Literals
+// that can't be compared with their fields (e.g. an invalid
+// pattern) don't stop the parsing; instead, all of them are
+// returned at once, as an errorx.Errors of *PathErrors.
+func ParseExpr(filterJson []byte) (Expr, error) {
+	return parseExpr(filterJson, parseOptions{})
+}
+
+// MustParseExpr is a convenience function that wraps ParseExpr,
+// but panics if an error occurs.
+func MustParseExpr(filterJson []byte) Expr {
+	expr, err := ParseExpr(filterJson)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+// GetExpr marshals the given filter object to JSON, and
+// decodes the result into an expression tree.
+func GetExpr(filter any) (Expr, error) {
+	filterJson, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	return ParseExpr(filterJson)
+}
+
+// parseOptions restricts the filters that parseExpr accepts.
+type parseOptions struct {
+	// schema describes the fields that can be used in the
+	// filter; if it is nil, any field name is accepted.
+	schema *schemaField
+}
+
+// parseExpr decodes the JSON representation of a filter into
+// an expression tree, subject to the given options.
+func parseExpr(filterJson []byte, opts parseOptions) (Expr, error) {
+	p := parser{
+		decoder: json.NewDecoder(bytes.NewReader(filterJson)),
+		opts:    opts,
+		path:    []string{"$"},
+	}
+	p.decoder.UseNumber()
+	expr, err := p.parseClause()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, p.errorf(ErrInvalidFilter, "unexpected data after filter")
+	}
+	if len(p.problems) > 0 {
+		return nil, p.problems
+	}
+	if expr == nil {
+		expr = And{}
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	decoder  *json.Decoder
+	opts     parseOptions
+	path     []string
+	problems errorx.Errors
+	depth    int
+	clauses  int
+}
+
+// parseClause parses either a list of filter objects,
+// or a single filter object. A null or empty clause
+// results in a nil expression.
+func (p *parser) parseClause() (Expr, error) {
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case nil:
+		return nil, nil
+	case json.Delim('['):
+		exprs, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return and(exprs), nil
+	case json.Delim('{'):
+		exprs, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		return and(exprs), nil
+	}
+
+	return nil, p.errorf(ErrInvalidFilter, "expected a filter object or list, got '%v'", tok)
+}
+
+// parseList parses the elements of a list of filter objects; the
+// opening bracket must already have been consumed. Each element is
+// the conjunction of its clauses; empty elements are left out.
+func (p *parser) parseList() ([]Expr, error) {
+	var exprs []Expr
+	for i := 0; p.decoder.More(); i++ {
+		p.push(fmt.Sprintf("[%d]", i))
+		tok, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		if tok != json.Delim('{') {
+			return nil, p.errorf(ErrInvalidFilter, "expected a filter object, got '%v'", tok)
+		}
+		objectExprs, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		p.pop()
+		if expr := and(objectExprs); expr != nil {
+			exprs = append(exprs, expr)
+		}
+	}
+	if _, err := p.token(); err != nil {
+		return nil, err
+	}
+
+	return exprs, nil
+}
+
+// parseObject parses the clauses of a filter object, which are
+// AND'ed together; the opening brace must already have been consumed.
+func (p *parser) parseObject() ([]Expr, error) {
+	var exprs []Expr
+	for p.decoder.More() {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		p.push("." + key)
+		switch key {
+		case "and", "or", "not":
+			expr, err := p.parseNested(key)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				exprs = append(exprs, expr)
+			}
+		default:
+			field, err := p.field(p.opts.schema, key, false)
+			if err != nil {
+				return nil, err
+			}
+			tok, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			expr, err := p.parseOperators(tok, key, field)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				exprs = append(exprs, expr)
+			}
+		}
+		p.pop()
+	}
+	if _, err := p.token(); err != nil {
+		return nil, err
+	}
+
+	return exprs, nil
+}
+
+// parseNested parses the value of an "and", "or" or "not" clause.
+// The value of an "and" or "or" clause must be a list of filter
+// objects, which are AND'ed or OR'ed together, respectively; the
+// value of a "not" clause must be a single filter object, and is
+// negated. Null and empty clauses result in a nil expression.
+func (p *parser) parseNested(key string) (Expr, error) {
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case tok == nil:
+		return nil, nil
+	case key != "not" && tok == json.Delim('['):
+		exprs, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		if key == "or" {
+			return or(exprs), nil
+		}
+		return and(exprs), nil
+	case key == "not" && tok == json.Delim('{'):
+		exprs, err := p.parseObject()
+		if err != nil {
+			return nil, err
+		}
+		expr := and(exprs)
+		if expr == nil {
+			return nil, nil
+		}
+		return Not{Expr: expr}, nil
+	case key == "not":
+		return nil, p.errorf(ErrInvalidFilter, "expected a filter object, got '%v'", tok)
+	}
+
+	return nil, p.errorf(ErrInvalidFilter, "expected a list of filter objects, got '%v'", tok)
+}
+
+// parseOperators parses the operator object for the field with
+// the given path (e.g. `{"eq": 1985}`); the given token must be the
+// first token of the object. Multiple operators are AND'ed together;
+// operators with a null value are ignored.
+//
+// Any other key in the object is the name (or the dotted path) of
+// a field of the nested struct, and its value is the operator
+// object for that field; for example, these are equivalent:
+//
+//	`{"director": {"name": {"eq": "Robert Zemeckis"}}}`
+//	`{"director.name": {"eq": "Robert Zemeckis"}}`
+func (p *parser) parseOperators(tok json.Token, path string, field *schemaField) (Expr, error) {
+	if tok == nil {
+		return nil, nil
+	}
+	if tok != json.Delim('{') {
+		return nil, p.errorf(ErrInvalidFilter, "expected an operator object, got '%v'", tok)
+	}
+	var comparisons []Comparison
+	var nested And
+	var normalizationForm string
+	for p.decoder.More() {
+		operator, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		p.push("." + operator)
+		if operator == "normalize" {
+			normalizationForm, err = p.parseNormalizationForm()
+			if err != nil {
+				return nil, err
+			}
+			p.pop()
+			continue
+		}
+		if operator == "and" {
+			expr, err := p.parseOperatorList(path, field)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				nested = append(nested, expr)
+			}
+			p.pop()
+			continue
+		}
+		if _, ok := quantifiers[operator]; ok {
+			if err := p.checkOperator(operator, field); err != nil {
+				return nil, err
+			}
+			expr, err := p.parseQuantifier(path, operator, field)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				if err := p.countClause(); err != nil {
+					return nil, err
+				}
+				nested = append(nested, expr)
+			}
+			p.pop()
+			continue
+		}
+		op, ok := operators[operator]
+		if !ok {
+			expr, err := p.parseNestedField(path, operator, field)
+			if err != nil {
+				return nil, err
+			}
+			if expr != nil {
+				nested = append(nested, expr)
+			}
+			p.pop()
+			continue
+		}
+		if err := p.checkOperator(operator, field); err != nil {
+			return nil, err
+		}
+		value, err := p.parseValue(operator, op.value)
+		if err != nil {
+			return nil, err
+		}
+		value, err = p.parseDates(operator, op.value, value, field)
+		if err == nil {
+			err = p.checkLiteral(operator, op.value, value, field)
+		}
+		if err != nil {
+			// The literal itself has been decoded, so the rest
+			// of the filter can still be checked; see parseExpr.
+			p.problems = append(p.problems, err)
+			value = nil
+		}
+		if value != nil {
+			if err := p.countClause(); err != nil {
+				return nil, err
+			}
+			comparisons = append(comparisons, Comparison{Field: path, Operator: operator, Value: value})
+		}
+		p.pop()
+	}
+	if _, err := p.token(); err != nil {
+		return nil, err
+	}
+	and := And{}
+	for _, c := range comparisons {
+		c.Normalize = normalizationForm
+		and = append(and, c)
+	}
+	and = append(and, nested...)
+
+	switch len(and) {
+	case 0:
+		return nil, nil
+	case 1:
+		return and[0], nil
+	}
+	return and, nil
+}
+
+// parseOperatorList parses the "and" clause of an operator object,
+// which is a list of operator objects of the same field that are
+// AND'ed together; it combines comparisons that can't be in the same
+// operator object, such as two comparisons with the same operator.
+func (p *parser) parseOperatorList(path string, field *schemaField) (Expr, error) {
+	tok, err := p.token()
+	if err != nil || tok == nil {
+		return nil, err
+	}
+	if tok != json.Delim('[') {
+		return nil, p.errorf(ErrInvalidFilter, "expected a list of operator objects, got '%v'", tok)
+	}
+	var exprs []Expr
+	for i := 0; p.decoder.More(); i++ {
+		p.push(fmt.Sprintf("[%d]", i))
+		tok, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		expr, err := p.parseOperators(tok, path, field)
+		if err != nil {
+			return nil, err
+		}
+		if expr != nil {
+			exprs = append(exprs, expr)
+		}
+		p.pop()
+	}
+	if _, err := p.token(); err != nil {
+		return nil, err
+	}
+
+	return and(exprs), nil
+}
+
+// parseQuantifier parses the operator object of a quantifier
+// (e.g. `{"any": {"eq": "Comedy"}}`) of the list field with the
+// given path. The operator object of "any", "all" and "none"
+// applies to the elements of the list; if the elements are
+// structs, it can refer to their fields, in the same way as
+// the operator object of a nested struct. The operator object
+// of "size" applies to the number of elements.
+func (p *parser) parseQuantifier(path, quantifier string, field *schemaField) (Expr, error) {
+	var elem *schemaField
+	if field != nil {
+		if field.elem == nil {
+			return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires a list field", quantifier)
+		}
+		elem = field.elem
+	}
+	if quantifier == "size" {
+		elem = &schemaField{typ: reflect.TypeFor[int]()}
+	}
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOperators(tok, elementVariable, elem)
+	if err != nil || expr == nil {
+		return nil, err
+	}
+
+	return Quantifier{Field: path, Quantifier: quantifier, Expr: expr}, nil
+}
+
+// parseNestedField parses the operator object of a field of the
+// nested struct of the field with the given path. A key that is
+// not the name of a nested field is reported as an unknown operator.
+func (p *parser) parseNestedField(path, key string, field *schemaField) (Expr, error) {
+	if field != nil && len(field.fields) == 0 {
+		return nil, p.errorf(ErrInvalidOperator, "'%s'", key)
+	}
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	if field == nil && tok != nil && tok != json.Delim('{') {
+		return nil, p.errorf(ErrInvalidOperator, "'%s'", key)
+	}
+	nestedField, err := p.field(field, key, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseOperators(tok, path+"."+key, nestedField)
+}
+
+// field checks that the given key is the name (or the dotted
+// path) of a field of the given schema, and returns the schema
+// of that field; if the given schema is nil, any non-empty field
+// name is accepted, and the result is nil. Without a schema, the
+// segments of a nested key (or of a dotted path, after the first
+// one) can also be the index of an element of a list.
+func (p *parser) field(schema *schemaField, key string, nested bool) (*schemaField, error) {
+	field := schema
+	for i, name := range strings.Split(key, ".") {
+		leadingIndex := validListIndex.MatchString(name) && !nested && i == 0
+		if name == "" || leadingIndex {
+			return nil, p.errorf(ErrUnknownField, "'%s'", key)
+		}
+		if field == nil {
+			continue
+		}
+		nested, ok := field.field(name)
+		if !ok {
+			return nil, p.errorf(ErrUnknownField, "'%s'", key)
+		}
+		field = nested
+	}
+
+	return field, nil
+}
+
+// parseValue parses the literal value of an operator, and
+// checks that it is of the kind that the operator accepts.
+// A null value results in a nil literal.
+func (p *parser) parseValue(operator string, kind valueKind) (any, error) {
+	tok, err := p.token()
+	if err != nil {
+		return nil, err
+	}
+	if tok == nil {
+		return nil, nil
+	}
+	if kind == customValue {
+		return p.parseJSONValue(tok)
+	}
+	if tok != json.Delim('[') {
+		if kind == listValue || kind == rangeValue || !kind.accepts(tok) {
+			return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires %s", operator, kind)
+		}
+		return tok, nil
+	}
+	if kind != listValue && kind != rangeValue {
+		return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires %s", operator, kind)
+	}
+	values := []any{}
+	for p.decoder.More() {
+		elem, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		if !kind.accepts(elem) {
+			return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires %s", operator, kind)
+		}
+		values = append(values, elem)
+	}
+	if _, err := p.token(); err != nil {
+		return nil, err
+	}
+	if kind == rangeValue && len(values) != 2 {
+		return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires %s", operator, kind)
+	}
+
+	return values, nil
+}
+
+// parseJSONValue parses an arbitrary JSON value (the literal
+// of a custom operator), of which the given token is the first.
+func (p *parser) parseJSONValue(tok json.Token) (any, error) {
+	switch tok {
+	case json.Delim('['):
+		values := []any{}
+		for p.decoder.More() {
+			elem, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.parseJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		if _, err := p.token(); err != nil {
+			return nil, err
+		}
+		return values, nil
+	case json.Delim('{'):
+		values := map[string]any{}
+		for p.decoder.More() {
+			key, err := p.parseKey()
+			if err != nil {
+				return nil, err
+			}
+			elem, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.parseJSONValue(elem)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = value
+		}
+		if _, err := p.token(); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+
+	return tok, nil
+}
+
+// parseDates checks the dates in the literal of an operator that
+// is applied to a date field (or that requires a date, such as
+// "before"), and converts them into RFC 3339 dates, so that they
+// can be compared without the format of the field. Relative dates
+// (e.g. "now-7d") are left as-is, because they are resolved
+// when the filter is evaluated.
+func (p *parser) parseDates(operator string, kind valueKind, value any, field *schemaField) (any, error) {
+	timeField := field != nil && isTime(field.typ)
+	switch {
+	case value == nil:
+		return nil, nil
+	case (kind == timeValue || kind == durationValue) && field != nil && !timeField:
+		return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires a date field", operator)
+	case kind == durationValue:
+		if _, err := parseDuration(value.(string)); err != nil {
+			return nil, p.errorf(ErrTypeMismatch, "%s", err)
+		}
+		return value, nil
+	case kind != timeValue && (!timeField || kind == stringValue || kind == boolValue || kind == customValue):
+		return value, nil
+	}
+	var format string
+	if field != nil {
+		format = field.format
+	}
+	parseDate := func(v any) (any, error) {
+		s, ok := v.(string)
+		if !ok {
+			return nil, p.errorf(ErrTypeMismatch, "operator '%s' requires a date", operator)
+		}
+		t, relative, err := parseTime(s, format)
+		if err != nil {
+			return nil, p.errorf(ErrTypeMismatch, "%s", err)
+		}
+		if relative {
+			return s, nil
+		}
+		return t.Format(time.RFC3339Nano), nil
+	}
+	list, ok := value.([]any)
+	if !ok {
+		return parseDate(value)
+	}
+	dates := make([]any, len(list))
+	for i, elem := range list {
+		date, err := parseDate(elem)
+		if err != nil {
+			return nil, err
+		}
+		dates[i] = date
+	}
+
+	return dates, nil
+}
+
+// checkLiteral checks that the literal of an operator can be compared
+// with the field that the operator is applied to: the operator must
+// apply to the kind of the field (e.g. "contains" doesn't apply to a
+// numeric field), and the literal must be of the same kind as the
+// field (e.g. a numeric field can't be compared with a string). It
+// also checks that the patterns of "matches" and "imatches" compile.
+// Without a schema, only the patterns are checked.
+func (p *parser) checkLiteral(operator string, kind valueKind, value any, field *schemaField) error {
+	if value == nil {
+		return nil
+	}
+	if operator == "matches" || operator == "imatches" {
+		if err := p.checkPattern(value.(string)); err != nil {
+			return err
+		}
+	}
+	if field == nil || len(field.fields) > 0 || field.elem != nil {
+		return nil
+	}
+	leaf := leafKindOf(field.typ)
+	if operatorValueSchema(kind, leaf) == nil {
+		return p.errorf(ErrTypeMismatch, "operator '%s' can't be applied to a field of type %s", operator, leaf)
+	}
+	if kind != primitiveValue && kind != orderedValue && kind != listValue && kind != rangeValue {
+		return nil
+	}
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+	for _, v := range values {
+		if !leaf.accepts(v) {
+			return p.errorf(ErrTypeMismatch, "operator '%s' requires a value of type %s, got %s", operator, leaf, literal(v))
+		}
+	}
+
+	return nil
+}
+
+// parseNormalizationForm parses the value of the
+// "normalize" option of an operator object.
+func (p *parser) parseNormalizationForm() (string, error) {
+	tok, err := p.token()
+	if err != nil {
+		return "", err
+	}
+	if tok == nil {
+		return "", nil
+	}
+	form, ok := tok.(string)
+	if !ok {
+		return "", p.errorf(ErrTypeMismatch, "option 'normalize' requires a string")
+	}
+	if !normalizationForms[form] {
+		return "", p.errorf(ErrInvalidOperator, "unknown normalization form '%s'", form)
+	}
+
+	return form, nil
+}
+
+func (p *parser) parseKey() (string, error) {
+	tok, err := p.token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := tok.(string)
+	if !ok {
+		return "", p.errorf(ErrInvalidFilter, "expected an object key, got '%v'", tok)
+	}
+	return key, nil
+}
+
+// token reads the next JSON token, converting any
+// decoding error into an ErrInvalidFilter.
+func (p *parser) token() (json.Token, error) {
+	tok, err := p.decoder.Token()
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, p.errorf(ErrInvalidFilter, "%s", err)
+	}
+	if err := p.checkDepth(tok); err != nil {
+		return nil, err
+	}
+	return tok, nil
+}
+
+func (p *parser) push(segment string) {
+	p.path = append(p.path, segment)
+}
+
+func (p *parser) pop() {
+	p.path = p.path[:len(p.path)-1]
+}
+
+func (p *parser) errorf(sentinel error, format string, args ...any) error {
+	return &PathError{
+		Path: strings.Join(p.path, ""),
+		Err:  fmt.Errorf("%w: %s", sentinel, fmt.Sprintf(format, args...)),
+	}
+}
+
+// and combines the given expressions into their conjunction;
+// the result is nil if there are no expressions.
+func and(exprs []Expr) Expr {
+	if len(exprs) == 0 {
+		return nil
+	}
+	return simplify(And(exprs))
+}
+
+// or combines the given expressions into their disjunction;
+// the result is nil if there are no expressions.
+func or(exprs []Expr) Expr {
+	switch len(exprs) {
+	case 0:
+		return nil
+	case 1:
+		return exprs[0]
+	}
+	return Or(exprs)
+}
+
+func simplify(a And) Expr {
+	if len(a) == 1 {
+		return a[0]
+	}
+	return a
+}
+
+// mapStrings applies fn to the given literal if it is
+// a string, or to its string elements if it is a list.
+func mapStrings(value any, fn func(string) string) any {
+	switch val := value.(type) {
+	case string:
+		return fn(val)
+	case []any:
+		mapped := make([]any, len(val))
+		for i, elem := range val {
+			mapped[i] = mapStrings(elem, fn)
+		}
+		return mapped
+	}
+
+	return value
+}
+
+// isAtomic reports whether the expression of the given node
+// can be combined with other expressions without parentheses.
+func isAtomic(e Expr) bool {
+	switch e.(type) {
+	case Comparison, Not, Quantifier:
+		return true
+	}
+	return false
+}
+
+func joinExpressions(exprs []Expr, separator string) string {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		parts[i] = e.Expression()
+		if !isAtomic(e) && len(exprs) > 1 {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+	return strings.Join(parts, separator)
+}
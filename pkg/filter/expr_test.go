@@ -0,0 +1,262 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorz"
+)
+
+type exprTestCase struct {
+	name       string
+	filterJSON string
+	expression string
+}
+
+func TestParseExpr(t *testing.T) {
+	cases := []exprTestCase{
+		{
+			name:       "simple filter",
+			filterJSON: `[{"kind": {"eq": "MOVIE"}}]`,
+			expression: `$["kind"] == "MOVIE"`,
+		},
+		{
+			name:       "single object",
+			filterJSON: `{"movieYear": {"gte": 1980}}`,
+			expression: `$["movieYear"] >= 1980`,
+		},
+		{
+			name:       "multiple operators on a field",
+			filterJSON: `[{"movieYear": {"gte": 1980, "lt": 1990}}]`,
+			expression: `$["movieYear"] >= 1980 && $["movieYear"] < 1990`,
+		},
+		{
+			name:       "implied logical 'and'",
+			filterJSON: `[{"title": {"matches": "Back to the .*"}}, {"movieYear": {"eq": 1985}}]`,
+			expression: `$["title"] =~ "Back to the .*" && $["movieYear"] == 1985`,
+		},
+		{
+			name:       "explicit logical 'or'",
+			filterJSON: `{"or": [{"movieYear": {"eq": 1955}}, {"title": {"matches": "Back to the .*"}}]}`,
+			expression: `$["movieYear"] == 1955 || $["title"] =~ "Back to the .*"`,
+		},
+		{
+			name:       "'or' with a single element",
+			filterJSON: `[{"movieYear": {"eq": 1955}}, {"or": [{"title": {"matches": "Back to the .*"}}]}]`,
+			expression: `$["movieYear"] == 1955 && $["title"] =~ "Back to the .*"`,
+		},
+		{
+			name:       "nested 'or' and 'and'",
+			filterJSON: `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"eq": 1985}}, {"and": [{"title": {"eq": "Back to the Future"}}, {"kind": {"eq": "MOVIE"}}]}]}]`,
+			expression: `$["movieYear"] == 1955 && ($["movieYear"] == 1985 || ($["title"] == "Back to the Future" && $["kind"] == "MOVIE"))`,
+		},
+		{
+			name:       "'or' inside an object",
+			filterJSON: `{"kind": {"eq": "MOVIE"}, "or": [{"movieYear": {"eq": 1985}}, {"movieYear": {"eq": 1955}}]}`,
+			expression: `$["kind"] == "MOVIE" && ($["movieYear"] == 1985 || $["movieYear"] == 1955)`,
+		},
+		{
+			name:       "'or' before the fields of an object",
+			filterJSON: `{"or": [{"movieYear": {"eq": 1985}}, {"movieYear": {"eq": 1955}}], "kind": {"eq": "MOVIE"}}`,
+			expression: `($["movieYear"] == 1985 || $["movieYear"] == 1955) && $["kind"] == "MOVIE"`,
+		},
+		{
+			name:       "'not'",
+			filterJSON: `[{"kind": {"eq": "MOVIE"}}, {"not": {"movieYear": {"lt": 1980}}}]`,
+			expression: `$["kind"] == "MOVIE" && !($["movieYear"] < 1980)`,
+		},
+		{
+			name:       "'not' inside 'or'",
+			filterJSON: `{"or": [{"kind": {"eq": "MOVIE"}}, {"not": {"movieYear": {"lt": 1980}}}]}`,
+			expression: `$["kind"] == "MOVIE" || !($["movieYear"] < 1980)`,
+		},
+		{
+			name:       "'and' and 'not' inside 'or'",
+			filterJSON: `{"or": [{"and": [{"kind": {"eq": "MOVIE"}}]}, {"not": {"title": {"eq": "x"}, "or": [{"movieYear": {"eq": 1985}}, {"movieYear": {"eq": 1955}}]}}]}`,
+			expression: `$["kind"] == "MOVIE" || !($["title"] == "x" && ($["movieYear"] == 1985 || $["movieYear"] == 1955))`,
+		},
+		{
+			name:       "null operators are ignored",
+			filterJSON: `[{"kind": {"eq": null, "ne": "SERIES"}, "title": null, "and": null}]`,
+			expression: `$["kind"] != "SERIES"`,
+		},
+		{
+			name:       "set membership",
+			filterJSON: `[{"movieYear": {"in": [1985, 1994]}}, {"kind": {"nin": ["SERIES"]}}]`,
+			expression: `isIn($["movieYear"], [1985, 1994]) && !isIn($["kind"], ["SERIES"])`,
+		},
+		{
+			name:       "substring",
+			filterJSON: `[{"title": {"contains": "the", "startsWith": "Back", "endsWith": "Future"}}]`,
+			expression: `contains($["title"], "the") && startsWith($["title"], "Back") && endsWith($["title"], "Future")`,
+		},
+		{
+			name:       "presence",
+			filterJSON: `[{"tagline": {"exists": true}}, {"description": {"isNull": false}}]`,
+			expression: `!isNull($["tagline"]) && !isNull($["description"])`,
+		},
+		{
+			name:       "range",
+			filterJSON: `[{"movieYear": {"between": [1980, 1989]}}]`,
+			expression: `($["movieYear"] >= 1980 && $["movieYear"] <= 1989)`,
+		},
+		{
+			name:       "case-insensitive",
+			filterJSON: `[{"title": {"ieq": "Back To The Future", "imatches": "^back"}}]`,
+			expression: `fold($["title"]) == "back to the future" && $["title"] =~ "(?i)^back"`,
+		},
+		{
+			name:       "normalized",
+			filterJSON: `[{"title": {"normalize": "unaccent", "icontains": "Amélie"}}]`,
+			expression: `contains(fold(normalize($["title"], "unaccent")), "amelie")`,
+		},
+		{
+			name:       "nested field",
+			filterJSON: `[{"director": {"name": {"eq": "Robert Zemeckis"}}}]`,
+			expression: `$["director"]["name"] == "Robert Zemeckis"`,
+		},
+		{
+			name:       "dotted path",
+			filterJSON: `[{"director.name": {"eq": "Robert Zemeckis"}}]`,
+			expression: `$["director"]["name"] == "Robert Zemeckis"`,
+		},
+		{
+			name:       "operators and nested fields",
+			filterJSON: `[{"director": {"exists": true, "birthYear": {"lt": 1960}, "address.city": {"ieq": "Chicago"}}}]`,
+			expression: `!isNull($["director"]) && $["director"]["birthYear"] < 1960 && fold($["director"]["address"]["city"]) == "chicago"`,
+		},
+		{
+			name:       "quantifiers",
+			filterJSON: `[{"genres": {"any": {"eq": "Comedy"}, "size": {"gt": 2}}}]`,
+			expression: `anyOf($["genres"], "$[\"elem\"] == \"Comedy\"") && sizeOf($["genres"], "$[\"elem\"] > 2")`,
+		},
+		{
+			name:       "nested quantifiers",
+			filterJSON: `[{"cast": {"all": {"name": {"ne": "Biff Tannen"}, "roles": {"none": {"eq": "villain"}}}}}]`,
+			expression: `allOf($["cast"], "$[\"elem\"][\"name\"] != \"Biff Tannen\" && noneOf($[\"elem\"][\"roles\"], \"$[\\\"elem\\\"] == \\\"villain\\\"\")")`,
+		},
+		{
+			name:       "'and' in an operator object",
+			filterJSON: `[{"cast": {"any": {"name": {"ne": "Biff Tannen"}, "and": [{"name": {"ne": "Griff Tannen"}}]}}}]`,
+			expression: `anyOf($["cast"], "$[\"elem\"][\"name\"] != \"Biff Tannen\" && $[\"elem\"][\"name\"] != \"Griff Tannen\"")`,
+		},
+		{
+			name:       "dates",
+			filterJSON: `[{"releaseDate": {"after": "1985-07-03", "before": "now-7d", "within": "72h"}}]`,
+			expression: `$["releaseDate"] > "1985-07-03T00:00:00Z" && $["releaseDate"] < "now-7d" && within($["releaseDate"], "72h")`,
+		},
+		{
+			name:       "empty filter",
+			filterJSON: `[]`,
+			expression: `true`,
+		},
+		{
+			name:       "string containing JSON syntax",
+			filterJSON: `[{"title": {"eq": "{eq\"foo\": [1, 2]}, {or"}}]`,
+			expression: `$["title"] == "{eq\"foo\": [1, 2]}, {or"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			expr, err := ParseExpr([]byte(tc.filterJSON))
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expression, expr.Expression())
+		})
+	}
+}
+
+func TestParseExpr_Tree(t *testing.T) {
+	filterJSON := `[{"kind": {"eq": "MOVIE"}}, {"or": [{"movieYear": {"eq": 1985}}, {"title": {"eq": "Back to the Future"}, "movieYear": {"eq": 1990}}]}]`
+
+	expr, err := ParseExpr([]byte(filterJSON))
+
+	require.NoError(t, err)
+	assert.Equal(t, And{
+		Comparison{Field: "kind", Operator: "eq", Value: "MOVIE"},
+		Or{
+			Comparison{Field: "movieYear", Operator: "eq", Value: json.Number("1985")},
+			And{
+				Comparison{Field: "title", Operator: "eq", Value: "Back to the Future"},
+				Comparison{Field: "movieYear", Operator: "eq", Value: json.Number("1990")},
+			},
+		},
+	}, expr)
+}
+
+func TestCompile_Quantifiers(t *testing.T) {
+	expr, err := ParseExpr([]byte(`{"cast": {"any": {"name": {"eq": "Christopher Lloyd"}, "roles": {"none": {"eq": "villain"}}}}}`))
+	require.NoError(t, err)
+
+	elements := elementEvaluables{}
+	require.NoError(t, elements.compile(expr))
+	assert.Len(t, elements, 2)
+
+	evaluable, err := Compile(expr)
+	require.NoError(t, err)
+	result, err := evaluable.EvalBool(context.Background(), map[string]any{
+		"cast": []any{map[string]any{"name": "Christopher Lloyd", "roles": []any{"scientist"}}},
+	})
+	require.NoError(t, err)
+	assert.True(t, result)
+}
+
+type exprErrorTestCase struct {
+	name       string
+	filterJSON string
+	err        error
+	path       string
+}
+
+func TestParseExpr_Errors(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "malformed JSON", filterJSON: `[{"kind": {"eq": "MOVIE"}`, err: ErrInvalidFilter, path: "$[0]"},
+		{name: "trailing data", filterJSON: `[{"kind": {"eq": "MOVIE"}}] []`, err: ErrInvalidFilter, path: "$"},
+		{name: "unknown operator", filterJSON: `[{"kind": {"like": "MOVIE"}}]`, err: ErrInvalidOperator, path: "$[0].kind.like"},
+		{name: "empty field name", filterJSON: `[{"director..name": {"eq": "Robert Zemeckis"}}]`, err: ErrUnknownField, path: "$[0].director..name"},
+		{name: "non-primitive value", filterJSON: `[{"kind": {"eq": ["MOVIE"]}}]`, err: ErrTypeMismatch, path: "$[0].kind.eq"},
+		{name: "numeric regex", filterJSON: `[{"or": [{"title": {"matches": 1985}}]}]`, err: ErrTypeMismatch, path: "$[0].or[0].title.matches"},
+		{name: "boolean ordering", filterJSON: `{"title": {"gt": true}}`, err: ErrTypeMismatch, path: "$.title.gt"},
+		{name: "scalar set", filterJSON: `[{"kind": {"in": "MOVIE"}}]`, err: ErrTypeMismatch, path: "$[0].kind.in"},
+		{name: "nested set", filterJSON: `[{"kind": {"in": [["MOVIE"]]}}]`, err: ErrTypeMismatch, path: "$[0].kind.in"},
+		{name: "numeric substring", filterJSON: `[{"title": {"contains": 1}}]`, err: ErrTypeMismatch, path: "$[0].title.contains"},
+		{name: "non-boolean presence", filterJSON: `[{"title": {"exists": "yes"}}]`, err: ErrTypeMismatch, path: "$[0].title.exists"},
+		{name: "incomplete range", filterJSON: `[{"movieYear": {"between": [1980]}}]`, err: ErrTypeMismatch, path: "$[0].movieYear.between"},
+		{name: "unknown normalization form", filterJSON: `[{"title": {"ieq": "x", "normalize": "NFX"}}]`, err: ErrInvalidOperator, path: "$[0].title.normalize"},
+		{name: "numeric case-insensitive", filterJSON: `[{"movieYear": {"ieq": 1985}}]`, err: ErrTypeMismatch, path: "$[0].movieYear.ieq"},
+		{name: "invalid pattern", filterJSON: `[{"title": {"imatches": "back to the ("}}]`, err: ErrInvalidPattern, path: "$[0].title.imatches"},
+		{name: "invalid date", filterJSON: `[{"releaseDate": {"before": "1985-13-01"}}]`, err: ErrTypeMismatch, path: "$[0].releaseDate.before"},
+		{name: "invalid duration", filterJSON: `[{"updatedAt": {"within": 72}}]`, err: ErrTypeMismatch, path: "$[0].updatedAt.within"},
+		{name: "'not' with a list", filterJSON: `[{"not": [{"kind": {"eq": "MOVIE"}}]}]`, err: ErrInvalidFilter, path: "$[0].not"},
+		{name: "'or' with an object", filterJSON: `[{"or": {"kind": {"eq": "MOVIE"}}}]`, err: ErrInvalidFilter, path: "$[0].or"},
+		{name: "nested field is not an object", filterJSON: `[{"director": {"name": "Robert Zemeckis"}}]`, err: ErrInvalidOperator, path: "$[0].director.name"},
+		{name: "invalid path", filterJSON: `[{"director.": {"eq": "Robert Zemeckis"}}]`, err: ErrUnknownField, path: "$[0].director."},
+		{name: "operator is not an object", filterJSON: `[{"kind": "MOVIE"}]`, err: ErrInvalidFilter, path: "$[0].kind"},
+		{name: "list element is not an object", filterJSON: `["kind"]`, err: ErrInvalidFilter, path: "$[0]"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseExpr([]byte(tc.filterJSON))
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorIs(t, err, errorz.ErrBadRequest)
+			var pathErr *PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, tc.path, pathErr.Path)
+		})
+	}
+}
+
+func TestShouldInclude_StringContainingJSONSyntax(t *testing.T) {
+	movie := Movie{Title: `Foo {eq "bar": [1, 2]}, {or`}
+	structFilterJson := `[{"title": {"eq": "Foo {eq \"bar\": [1, 2]}, {or"}}]`
+	structFilter := NewStructFilter[Movie](structFilterJson)
+
+	assert.True(t, structFilter.ShouldInclude(movie))
+	assert.False(t, structFilter.ShouldInclude(testMovie))
+}
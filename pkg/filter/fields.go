@@ -0,0 +1,314 @@
+package filter
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PaesslerAG/gval"
+
+	"github.com/tartale/go/pkg/jsontime"
+)
+
+// structFields caches the filterable fields of each
+// struct type; see filterableFields.
+var structFields sync.Map
+
+// schemas caches the schema of each filtered type;
+// see schemaOf.
+var schemas sync.Map
+
+// structField is a field of a struct that can be
+// used in a filter, along with its JSON name.
+type structField struct {
+	reflect.StructField
+	name string
+}
+
+// filterableFields returns the fields of the given struct type
+// that can be used in a filter, in the same way that encoding/json
+// chooses the fields to marshal: unexported fields and fields with
+// a json tag of "-" are skipped, and the fields of embedded structs
+// without a json name are promoted. Fields with a filter tag of "-"
+// are also skipped, so that clients can't filter by them. The Index of each field is
+// relative to the given type.
+func filterableFields(t reflect.Type) []structField {
+	if fields, ok := structFields.Load(t); ok {
+		return fields.([]structField)
+	}
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || field.Tag.Get("filter") == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			for _, embedded := range filterableFields(fieldType) {
+				embedded.Index = append([]int{i}, embedded.Index...)
+				fields = append(fields, embedded)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{StructField: field, name: name})
+	}
+	structFields.Store(t, fields)
+
+	return fields
+}
+
+// nestedStruct returns the struct type of the given field
+// type (or the type it points to), if it has any fields that
+// can be used in a filter; otherwise, it returns nil. Structs
+// without any such fields (e.g. time.Time) are compared as a
+// whole, in the same way as primitive values.
+func nestedStruct(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || len(filterableFields(t)) == 0 {
+		return nil
+	}
+	return t
+}
+
+// schemaField describes a field that can be used in a
+// filter, along with the fields of its nested struct (if
+// any), or the schema of its elements, if it is a list.
+// The format is the format tag of the field (see jsontime),
+// which is used to parse the dates that it is compared with,
+// the column is its db tag (see ToSQL), and the operators are
+// the ones that its filter tag allows (see allowedOperators).
+type schemaField struct {
+	name      string
+	goName    string
+	typ       reflect.Type
+	format    string
+	column    string
+	operators map[string]bool
+	fields    []*schemaField
+	elem      *schemaField
+}
+
+// field returns the nested field with the given JSON name.
+func (f *schemaField) field(name string) (*schemaField, bool) {
+	for _, nested := range f.fields {
+		if nested.name == name {
+			return nested, true
+		}
+	}
+	return nil, false
+}
+
+// schemaOf returns the schema of the given struct type; the
+// root of the schema is a field with the type itself.
+func schemaOf(t reflect.Type) *schemaField {
+	if schema, ok := schemas.Load(t); ok {
+		return schema.(*schemaField)
+	}
+	schema := &schemaField{typ: t, fields: schemaFields(t, map[reflect.Type]bool{t: true})}
+	schemas.Store(t, schema)
+
+	return schema
+}
+
+// schemaFields returns the schema of each filterable
+// field of the given struct type.
+func schemaFields(t reflect.Type, visiting map[reflect.Type]bool) []*schemaField {
+	var fields []*schemaField
+	for _, field := range filterableFields(t) {
+		f := newSchemaField(field.name, field.Name, field.Type, field.Tag.Get("format"), visiting)
+		f.column, _, _ = strings.Cut(field.Tag.Get("db"), ",")
+		// The elements of a list field are subject to the
+		// same operators as the field.
+		operators := allowedOperators(field.Tag.Get("filter"))
+		for elem := f; elem != nil; elem = elem.elem {
+			elem.operators = operators
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// newSchemaField returns the schema of a field of the given type.
+// The fields of a nested struct that is already being visited
+// (i.e. a recursive type) are not included, so that the schema
+// is finite.
+func newSchemaField(name, goName string, t reflect.Type, format string, visiting map[reflect.Type]bool) *schemaField {
+	f := &schemaField{name: name, goName: goName, typ: t, format: format}
+	if elem := listElem(t); elem != nil {
+		f.elem = newSchemaField("", "", elem, format, visiting)
+		return f
+	}
+	nested := nestedStruct(t)
+	if nested == nil || visiting[nested] {
+		return f
+	}
+	visiting[nested] = true
+	for _, nestedField := range schemaFields(nested, visiting) {
+		// A nested field that has the name of an operator
+		// would be ambiguous, so it can't be filtered.
+		if isReserved(nestedField.name) {
+			continue
+		}
+		f.fields = append(f.fields, nestedField)
+	}
+	delete(visiting, nested)
+
+	return f
+}
+
+// listElem returns the element type of the given type (or the type it
+// points to), if it is a slice or array; byte slices are not lists.
+func listElem(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if (t.Kind() != reflect.Slice && t.Kind() != reflect.Array) || t.Elem().Kind() == reflect.Uint8 {
+		return nil
+	}
+	return t.Elem()
+}
+
+// isReserved reports whether the given key is the name of an
+// operator (or option, or clause) of filter.Operator.
+func isReserved(key string) bool {
+	_, isOperator := operators[key]
+	_, isQuantifier := quantifiers[key]
+	return isOperator || isQuantifier || key == "normalize" || key == "and"
+}
+
+// valueOf converts a value into the form that the expressions
+// of this package are evaluated against: structs become maps
+// keyed by the JSON names of their fields, slices become
+// lists of converted elements, pointers are dereferenced,
+// and primitive types are converted into their underlying
+// type (e.g. ShowKind into string).
+func valueOf(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if listElem(v.Type()) == nil {
+			break
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = valueOf(v.Index(i))
+		}
+		return values
+	case reflect.Struct:
+		if v.Type() == typeOfJSONTime {
+			return v.Interface().(jsontime.Time).Time
+		}
+		if nestedStruct(v.Type()) == nil {
+			return v.Interface()
+		}
+		values := map[string]any{}
+		for _, field := range filterableFields(v.Type()) {
+			fieldValue, err := v.FieldByIndexErr(field.Index)
+			if err != nil {
+				// The field is promoted through a nil embedded pointer.
+				values[field.name] = nil
+				continue
+			}
+			values[field.name] = valueOf(fieldValue)
+		}
+		return values
+	}
+	if !v.CanInterface() {
+		return nil
+	}
+
+	return v.Interface()
+}
+
+// selectVariable is the variable selector of the filter
+// language. Unlike the default selector of gval, selecting
+// a field of a missing or nil value results in nil, rather
+// than an error; for example, `director.name` is nil if the
+// director of a movie is nil.
+func selectVariable(path gval.Evaluables) gval.Evaluable {
+	return func(c context.Context, v any) (any, error) {
+		keys, err := path.EvalStrings(c, v)
+		if err != nil {
+			return nil, err
+		}
+		return selectPath(v, keys), nil
+	}
+}
+
+// parseRootVariable parses the variables that variable renders:
+// the "$" root (the parameter of the expression), followed by any
+// number of selectors (e.g. `$["cast"][0]["name"]`).
+func parseRootVariable(c context.Context, p *gval.Parser) (gval.Evaluable, error) {
+	var keys []gval.Evaluable
+	for {
+		if p.Scan() != '[' {
+			p.Camouflage("variable", '[')
+			return p.Var(keys...), nil
+		}
+		key, err := p.ParseExpression(c)
+		if err != nil {
+			return nil, err
+		}
+		if p.Scan() != ']' {
+			return nil, p.Expected("selector", ']')
+		}
+		keys = append(keys, key)
+	}
+}
+
+// selectPath selects the value at the given path of a value
+// returned by valueOf; the result is nil if there is no such path.
+func selectPath(v any, path []string) any {
+	for _, key := range path {
+		switch val := v.(type) {
+		case map[string]any:
+			v = val[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil
+			}
+			v = val[i]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
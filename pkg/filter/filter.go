@@ -1,11 +1,11 @@
 package filter
 
 import (
+	"context"
 	"fmt"
 	"iter"
 	"maps"
 	"reflect"
-	"regexp"
 	"slices"
 	"strings"
 
@@ -14,11 +14,6 @@ import (
 	"github.com/tartale/go/pkg/structs"
 )
 
-var (
-	quotedFields = regexp.MustCompile(`"(\w+)":`)
-	typeOfString = reflect.TypeFor[string]()
-)
-
 type Filterer interface {
 	ShouldInclude(val any) bool
 }
@@ -27,14 +22,105 @@ type FiltererOf[T any] interface {
 	ShouldInclude(val T) bool
 }
 
+type FiltererE interface {
+	ShouldIncludeE(val any) (bool, error)
+}
+
+type FiltererOfE[T any] interface {
+	ShouldIncludeE(val T) (bool, error)
+}
+
+// Operator defines the comparisons that can be applied to
+// a single field in a filter. When more than one operator
+// is set, all of them must be true for the field to match.
+//
+//   - eq, ne, lte, gte, lt, gt: compare the field with a value
+//   - matches: the field matches a regular expression
+//   - in, nin: the field is (not) equal to one of a list of values
+//   - contains, startsWith, endsWith: the field contains the given substring
+//   - exists, isNull: the field is (not) null, depending on the boolean value
+//   - between: the field is within an inclusive range, given as [min, max]
+//   - before, after: the field is a date before (after) the given date
+//   - within: the field is a date within the given duration (e.g. "72h", "7d"
+//     or "2w") of the current time, either before or after it
+//   - ieq, ine, icontains, istartsWith, iendsWith, imatches: case-insensitive
+//     versions of the string operators
+//   - any, all, none: the operator object is true for at least one, all, or none
+//     of the elements of a list field; for a list of structs, the operator object
+//     can refer to the fields of the elements
+//   - size: the operator object is true for the number of elements of a list field
+//
+// The normalize option applies a Unicode normalization form ("NFC", "NFD",
+// "NFKC", "NFKD", or "unaccent", which also removes diacritics) to both the
+// field and the values before they are compared; for example, this matches
+// a title of "Amélie":
+//
+//	{"title": {"ieq": "amelie", "normalize": "unaccent"}}
+//
+// The values of date fields (time.Time and jsontime.Time) are compared as
+// dates; the dates in a filter are parsed with the format tag of the field (the
+// same one that jsontime uses), or as RFC 3339 dates, and can also be relative
+// to the current time (e.g. "now-7d"):
+//
+//	{"releasedAt": {"gte": "2020-01-01", "before": "now-7d"}}
+//
+// The quantifiers take an operator object rather than a value, for example:
+//
+//	{"genres": {"any": {"eq": "Comedy"}}, "cast": {"size": {"gt": 2}}}
+//	{"cast": {"all": {"name": {"ne": "Biff Tannen"}}}}
+//
+// An operator object can't have the same operator twice; comparisons of
+// the same field can also be combined with an "and" list of operator
+// objects, which is how they are combined within a quantifier:
+//
+//	{"cast": {"any": {"and": [{"name": {"ne": "Biff Tannen"}}, {"name": {"ne": "Griff Tannen"}}]}}}
+//
+// Domain-specific operators can be added with RegisterOperator.
 type Operator struct {
-	Eq      any `json:"eq,omitempty"`
-	Ne      any `json:"ne,omitempty"`
-	Lte     any `json:"lte,omitempty"`
-	Gte     any `json:"gte,omitempty"`
-	Lt      any `json:"lt,omitempty"`
-	Gt      any `json:"gt,omitempty"`
-	Matches any `json:"matches,omitempty"`
+	Eq         any `json:"eq,omitempty"`
+	Ne         any `json:"ne,omitempty"`
+	Lte        any `json:"lte,omitempty"`
+	Gte        any `json:"gte,omitempty"`
+	Lt         any `json:"lt,omitempty"`
+	Gt         any `json:"gt,omitempty"`
+	Matches    any `json:"matches,omitempty"`
+	In         any `json:"in,omitempty"`
+	Nin        any `json:"nin,omitempty"`
+	Contains   any `json:"contains,omitempty"`
+	StartsWith any `json:"startsWith,omitempty"`
+	EndsWith   any `json:"endsWith,omitempty"`
+	Exists     any `json:"exists,omitempty"`
+	IsNull     any `json:"isNull,omitempty"`
+	Between    any `json:"between,omitempty"`
+	Before     any `json:"before,omitempty"`
+	After      any `json:"after,omitempty"`
+	Within     any `json:"within,omitempty"`
+
+	Ieq         any `json:"ieq,omitempty"`
+	Ine         any `json:"ine,omitempty"`
+	Icontains   any `json:"icontains,omitempty"`
+	IstartsWith any `json:"istartsWith,omitempty"`
+	IendsWith   any `json:"iendsWith,omitempty"`
+	Imatches    any `json:"imatches,omitempty"`
+
+	Any  any `json:"any,omitempty"`
+	All  any `json:"all,omitempty"`
+	None any `json:"none,omitempty"`
+	Size any `json:"size,omitempty"`
+
+	Normalize string `json:"normalize,omitempty"`
+
+	And []*Operator `json:"and,omitempty"`
+}
+
+// UnmarshalJSON unmarshals the JSON representation of an operator
+// object; its numbers are unmarshalled as json.Numbers rather than
+// as float64s, so that large integers (e.g. 1<<53 + 1) keep their
+// precision.
+func (o *Operator) UnmarshalJSON(data []byte) error {
+	type operator Operator
+
+	return unmarshalWithNumbers(data, (*operator)(o))
 }
 
 // GetExpression turns the JSON representation of the
@@ -45,14 +131,14 @@ type Operator struct {
 //
 //	`[{"kind": {"eq": "MOVIE"}}]`
 //
-// then the equivalent expression will be something like this:
+// then the equivalent expression will be:
 //
-//	`(kind == "MOVIE")`
+//	`$["kind"] == "MOVIE"`
 //
-// Note that, due to the simplistic nature of the conversion,
-// there may be innocuous artififacts in the resulting
-// expression, such as extra unnecessary parentheses. However,
-// the expression will be usable and correct.
+// The conversion is done by decoding the JSON into an
+// expression tree (see ParseExpr), so the fields and the
+// values in the filter are always rendered as properly-escaped
+// JSON paths and literals.
 func GetExpression(filter any) string {
 	filterValue := reflect.ValueOf(filter)
 	if !reflectx.IsSlice(filterValue.Interface()) {
@@ -76,30 +162,41 @@ func GetExpression(filter any) string {
 //		input:      {kind: "MOVIE", title: "Back to the Future"}
 //	  values:     {kind => "MOVIE"}
 func GetValues(filter, input any) map[string]any {
-	filterValue := reflect.ValueOf(filter)
-	if !reflectx.IsSlice(filterValue) {
+	values, err := GetValuesE(filter, input)
+	if err != nil {
+		panic(err)
+	}
+
+	return values
+}
+
+// GetValuesE is the same as GetValues, but returns an
+// ErrUnknownField error instead of panicking if the filter
+// contains a field that is not in the input.
+func GetValuesE(filter, input any) (map[string]any, error) {
+	if !reflectx.IsSlice(filter) {
 		filter = []any{filter}
 	}
+	filterValue := reflectx.ValueOfElement(filter)
 
 	values := map[string]any{}
 	for i := 0; i < filterValue.Len(); i++ {
 		f := filterValue.Index(i).Interface()
-		v := getValues(f, input)
+		v, err := getValues(f, input)
+		if err != nil {
+			return nil, &PathError{Path: fmt.Sprintf("$[%d]", i), Err: err}
+		}
 		maps.Copy(values, v)
 	}
 
-	return values
+	return values, nil
 }
 
 // Format does the full conversion of a JSON string into
-// a gval-compatible boolean expression.
+// a gval-compatible boolean expression. It panics if the
+// JSON is not a valid filter.
 func Format(expression string) string {
-	expression = removeQuotesOnFields(expression)
-	expression = replaceComparisonOperators(expression)
-	expression = replaceBrackets(expression)
-	expression = replaceLogicOperators(expression)
-
-	return expression
+	return MustParseExpr([]byte(expression)).Expression()
 }
 
 // ShouldInclude takes a filter and a value and
@@ -112,6 +209,33 @@ func ShouldInclude(filter, val any) bool {
 	return eval.(bool)
 }
 
+// ShouldIncludeE is the same as ShouldInclude, but returns
+// an error instead of panicking if the filter is invalid,
+// or can't be evaluated against the given value.
+func ShouldIncludeE(filter, val any) (bool, error) {
+	if !reflectx.IsSlice(filter) {
+		filter = []any{filter}
+	}
+	expr, err := GetExpr(filter)
+	if err != nil {
+		return false, err
+	}
+	values, err := GetValuesE(filter, val)
+	if err != nil {
+		return false, err
+	}
+	evaluable, err := Compile(expr)
+	if err != nil {
+		return false, err
+	}
+	eval, err := evaluable.EvalBool(context.Background(), values)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+	}
+
+	return eval, nil
+}
+
 // Filter takes a sequence iterator and returns
 // an iterator function that can be applied to that input sequence.
 func Filter(f Filterer, vals iter.Seq[any]) iter.Seq[any] {
@@ -154,88 +278,92 @@ func FilterAllFor[T any](f FiltererOf[T], vals []T) []T {
 	return slices.Collect(filterVals)
 }
 
-// removeQuotesOnFields removes all quotes from the input string;
-// this is used in the process of converting a JSON string into a
-// gval-compatible expression.
-func removeQuotesOnFields(s string) string {
-	return quotedFields.ReplaceAllString(s, "$1")
+// FilterAllE is the same as FilterAll, but stops and
+// returns the first error encountered while filtering.
+func FilterAllE(f FiltererE, vals []any) ([]any, error) {
+	var filterVals []any
+	for _, v := range vals {
+		ok, err := f.ShouldIncludeE(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filterVals = append(filterVals, v)
+		}
+	}
+
+	return filterVals, nil
 }
 
-// replaceComparisonOperators changes all of the names of the
-// operators in the given string to their gval-compatible equivalents
-// (e.g. 'eq' is converted to '=='); this is used in the process of
-// converting a JSON string into a gval-compatible expression.
-func replaceComparisonOperators(s string) string {
-	s = regexp.MustCompile(`{eq(.*?)}`).ReplaceAllString(s, " == $1 ")
-	s = regexp.MustCompile(`{ne(.*?)}`).ReplaceAllString(s, " != $1 ")
-	s = regexp.MustCompile(`{lte(.*?)}`).ReplaceAllString(s, " <= $1 ")
-	s = regexp.MustCompile(`{gte(.*?)}`).ReplaceAllString(s, " >= $1 ")
-	s = regexp.MustCompile(`{lt(.*?)}`).ReplaceAllString(s, " < $1 ")
-	s = regexp.MustCompile(`{gt(.*?)}`).ReplaceAllString(s, " > $1 ")
-	s = regexp.MustCompile(`{matches(.*?)}`).ReplaceAllString(s, " =~ $1 ")
+// FilterAllForE is the generics-compatible version of FilterAllE.
+func FilterAllForE[T any](f FiltererOfE[T], vals []T) ([]T, error) {
+	var filterVals []T
+	for _, v := range vals {
+		ok, err := f.ShouldIncludeE(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filterVals = append(filterVals, v)
+		}
+	}
 
-	return s
+	return filterVals, nil
 }
 
-// replaceBrackets converts all of the brackets in a JSON string
-// into parentheses; this is used in the process of
-// converting a JSON string into a gval-compatible expression.
-func replaceBrackets(s string) string {
-	return strings.NewReplacer(
-		`[`, `(`,
-		`]`, `)`,
-		`{`, `(`,
-		`}`, `)`,
-	).Replace(s)
-}
+// getValues returns the values of the fields of the input that
+// are used by the given filter object, including the fields used
+// by its "and", "or" and "not" clauses. The value of a field with
+// a nested filter object is the whole nested struct (see valueOf).
+func getValues(filter, input any) (map[string]any, error) {
+	values := map[string]any{}
+	err := collectValues(reflect.ValueOf(filter), structs.New(input), values)
+	if err != nil {
+		return nil, err
+	}
 
-// replaceLogicOperators changes all of the names of the logic
-// operators in the given string to their gval-compatible equivalents
-// (e.g. 'or' is converted to '||'); this is used in the process of
-// converting a JSON string into a gval-compatible expression.
-// One quirk of this replacement process is that a "," is interpreted
-// as an implicit "and" operator (because this is a somewhat natural
-// way to interpret a list in JSON). For example, an input JSON string:
-//
-//	`[{"title": {"matches": "Back to the .*"}}, {"movieYear": {"eq": 1985}}]`
-//
-// would be converted to:
-//
-//	`(title =~ "Back to the .*") && (movieYear == 1985)`
-func replaceLogicOperators(s string) string {
-	return strings.NewReplacer(
-		`,(or`, ` || (`,
-		`,(and`, ` && (`,
-		`,`, ` && `,
-	).Replace(s)
+	return values, nil
 }
 
-func getValues(filter, input any) map[string]any {
-	values := map[string]any{}
-	filterWalkFn := func(filterField reflect.StructField, filterValue reflect.Value) error {
+func collectValues(filterValue reflect.Value, input *structs.Struct, values map[string]any) error {
+	for filterValue.Kind() == reflect.Pointer || filterValue.Kind() == reflect.Interface {
 		if filterValue.IsNil() {
 			return nil
 		}
-		switch filterValue.Interface().(type) {
-		case *Operator:
-
-			inputField, ok := structs.New(input).FieldOk(filterField.Name)
-			if !ok {
-				panic(fmt.Errorf("filter contains a field that is not in the input: %s", filterField.Name))
+		filterValue = filterValue.Elem()
+	}
+	if filterValue.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < filterValue.NumField(); i++ {
+		filterField := filterValue.Type().Field(i)
+		fieldValue := filterValue.Field(i)
+		if !filterField.IsExported() || fieldValue.IsZero() {
+			continue
+		}
+		name, _, _ := strings.Cut(filterField.Tag.Get("json"), ",")
+		switch name {
+		case "and", "or":
+			for j := 0; j < fieldValue.Len(); j++ {
+				if err := collectValues(fieldValue.Index(j), input, values); err != nil {
+					return err
+				}
 			}
-			inputFieldName := inputField.TagRoot("json")
-			inputFieldValue := inputField.Value()
-			inputFieldReflectValue := reflect.ValueOf(inputFieldValue)
-			if inputFieldReflectValue.Kind() == reflect.String {
-				inputFieldValue = inputFieldReflectValue.Convert(typeOfString).Interface()
+			continue
+		case "not":
+			if err := collectValues(fieldValue, input, values); err != nil {
+				return err
 			}
-			values[inputFieldName] = inputFieldValue
+			continue
 		}
 
-		return nil
+		inputField, ok := input.FieldOk(filterField.Name)
+		if !ok {
+			return fmt.Errorf("%w '%s': filter contains a field that is not in the input", ErrUnknownField, filterField.Name)
+		}
+		inputFieldName := inputField.TagRoot("json")
+		values[inputFieldName] = valueOf(reflect.ValueOf(inputField.Value()))
 	}
 
-	structs.Walk(filter, filterWalkFn)
-
-	return values
+	return nil
 }
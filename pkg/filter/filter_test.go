@@ -1,9 +1,11 @@
 package filter
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/tartale/go/pkg/jsonx"
 )
 
@@ -49,12 +51,17 @@ func TestShouldInclude(t *testing.T) {
 		},
 		{
 			name:          "multi-field filter with explicit logical 'or' should include",
-			filterJSON:    `[{"movieYear": {"eq": 1955}}, {"or": [{"title": {"matches": "Back to the .*"}}]}]`,
+			filterJSON:    `[{"or": [{"movieYear": {"eq": 1955}}, {"title": {"matches": "Back to the .*"}}]}]`,
 			shouldInclude: true,
 		},
+		{
+			name:          "multi-field filter with explicit logical 'or' and a false sibling should exclude",
+			filterJSON:    `[{"movieYear": {"eq": 1955}}, {"or": [{"title": {"matches": "Back to the .*"}}]}]`,
+			shouldInclude: false,
+		},
 		{
 			name:          "multi-field nested filter should include",
-			filterJSON:    `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"eq": 1985}}, {"and": [{"title": {"eq": "Back to the Future"}}]}]}]`,
+			filterJSON:    `[{"title": {"matches": "Back to the .*"}}, {"or": [{"movieYear": {"eq": 1955}}, {"and": [{"movieYear": {"eq": 1985}}, {"kind": {"eq": "MOVIE"}}]}]}]`,
 			shouldInclude: true,
 		},
 		{
@@ -85,3 +92,88 @@ func TestShouldInclude(t *testing.T) {
 		})
 	}
 }
+
+func TestShouldIncludeE(t *testing.T) {
+	var movieFilters []*MovieFilter
+	jsonx.MustUnmarshal([]byte(`[{"title": {"matches": "Back to the .*"}}, {"movieYear": {"eq": 1985}}]`), &movieFilters)
+
+	result, err := ShouldIncludeE(movieFilters, testMovie)
+
+	require.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestShouldIncludeE_SingleFilter(t *testing.T) {
+	movieFilter := MovieFilter{Kind: &Operator{Eq: "SERIES"}}
+
+	result, err := ShouldIncludeE(movieFilter, testMovie)
+
+	require.NoError(t, err)
+	assert.False(t, result)
+}
+
+func TestGetValuesE_UnknownField(t *testing.T) {
+	type otherFilter struct {
+		Producer *Operator `json:"producer,omitempty"`
+	}
+	filters := []otherFilter{{Producer: &Operator{Eq: "Bob Gale"}}}
+
+	_, err := GetValuesE(filters, testMovie)
+
+	assert.ErrorIs(t, err, ErrUnknownField)
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, "$[0]", pathErr.Path)
+}
+
+func TestShouldInclude_HandWrittenNotFilter(t *testing.T) {
+	movieFilter := MovieFilter{
+		Kind: &Operator{Eq: "MOVIE"},
+		Not:  &MovieFilter{MovieYear: &Operator{Lt: 1980}},
+	}
+
+	assert.True(t, ShouldInclude(movieFilter, testMovie))
+
+	movieFilter.Not = &MovieFilter{Title: &Operator{Contains: "Future"}}
+
+	assert.False(t, ShouldInclude(movieFilter, testMovie))
+}
+
+func TestShouldInclude_HandWrittenNestedFilter(t *testing.T) {
+	movieFilter := MovieFilter{
+		Director: &PersonFilter{Name: &Operator{Eq: "Robert Zemeckis"}},
+		Studio:   &StudioFilter{Country: &Operator{In: []any{"US", "UK"}}},
+	}
+
+	assert.True(t, ShouldInclude(movieFilter, testMovieList[0]))
+	assert.False(t, ShouldInclude(movieFilter, testMovieList[1]))
+}
+
+func TestShouldInclude_HandWrittenQuantifierFilter(t *testing.T) {
+	movieFilter := MovieFilter{
+		Genres: &Operator{Any: &Operator{Eq: "Comedy"}},
+		Cast:   &Operator{None: &PersonFilter{Name: &Operator{Eq: "Morgan Freeman"}}},
+	}
+
+	assert.True(t, ShouldInclude(movieFilter, testMovieList[0]))
+	assert.False(t, ShouldInclude(movieFilter, testMovieList[1]))
+}
+
+func TestShouldInclude_HandWrittenDateFilter(t *testing.T) {
+	movieFilter := MovieFilter{
+		ReleaseDate: &Operator{After: "1990-01-01"},
+		UpdatedAt:   &Operator{Before: "now-7d"},
+	}
+
+	assert.False(t, ShouldInclude(movieFilter, testMovieList[0]))
+	assert.True(t, ShouldInclude(movieFilter, testMovieList[1]))
+}
+
+func TestOperator_UnmarshalJSON_LargeIntegers(t *testing.T) {
+	var operator Operator
+	jsonx.MustUnmarshal([]byte(`{"gte": 9007199254740993, "in": [1, 9223372036854775809]}`), &operator)
+
+	assert.Equal(t, json.Number("9007199254740993"), operator.Gte)
+	assert.Equal(t, `{"gte":9007199254740993,"in":[1,9223372036854775809]}`, jsonx.MustMarshalToString(operator))
+	assert.Equal(t, `$["id"] >= 9007199254740993 && isIn($["id"], [1, 9223372036854775809])`, GetExpression(map[string]any{"id": &operator}))
+}
@@ -0,0 +1,500 @@
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"time"
+	"unicode"
+
+	"github.com/PaesslerAG/gval"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// functions contains the gval functions that the
+// expressions generated for the operators of
+// filter.Operator rely on (e.g. "contains").
+//
+// Note that the functions use the context-aware signature
+// that gval calls directly; gval runs functions with any
+// other signature in a separate goroutine.
+var functions = gval.NewLanguage(
+	gval.Function("isIn", isIn),
+	gval.Function("contains", stringFunction("contains", strings.Contains)),
+	gval.Function("startsWith", stringFunction("startsWith", strings.HasPrefix)),
+	gval.Function("endsWith", stringFunction("endsWith", strings.HasSuffix)),
+	gval.Function("isNull", isNullFunction),
+	gval.Function("normalize", normalizeFunction),
+	gval.Function("fold", foldFunction),
+	gval.Function("anyOf", quantifierFunction("anyOf", true, true)),
+	gval.Function("allOf", quantifierFunction("allOf", false, false)),
+	gval.Function("noneOf", quantifierFunction("noneOf", true, false)),
+	gval.Function("sizeOf", sizeOfFunction),
+	gval.Function("within", withinFunction),
+	gval.Function("customOperator", customOperatorFunction),
+	comparisonOperator("==", equalOperator(true)),
+	comparisonOperator("!=", equalOperator(false)),
+	comparisonOperator("<", orderedOperator(func(c int) bool { return c < 0 })),
+	comparisonOperator("<=", orderedOperator(func(c int) bool { return c <= 0 })),
+	comparisonOperator(">", orderedOperator(func(c int) bool { return c > 0 })),
+	comparisonOperator(">=", orderedOperator(func(c int) bool { return c >= 0 })),
+	gval.PrefixExtension(scanner.Int, parseInteger),
+	gval.PrefixOperator("-", negateNumber),
+	gval.VariableSelector(selectVariable),
+	gval.PrefixExtension('$', parseRootVariable),
+)
+
+// comparisonOperator replaces the comparison operator of gval with
+// the given name. The operators of gval compare any two numbers as
+// float64s, so integers that a float64 can't represent (e.g. the
+// int64 1<<53 + 1) wouldn't be compared exactly.
+func comparisonOperator(name string, fn func(a, b any) (any, error)) gval.Language {
+	return gval.InfixEvalOperator(name, func(a, b gval.Evaluable) (gval.Evaluable, error) {
+		return func(ctx context.Context, parameter any) (any, error) {
+			av, err := a(ctx, parameter)
+			if err != nil {
+				return nil, err
+			}
+			bv, err := b(ctx, parameter)
+			if err != nil {
+				return nil, err
+			}
+			return fn(av, bv)
+		}, nil
+	})
+}
+
+// maxExactInteger is the largest integer up to
+// which a float64 can represent every integer.
+const maxExactInteger = 1 << 53
+
+// parseInteger parses an integer literal of the filter language
+// into a float64, as gval does, unless the float64 would not be
+// exact; the integer is then an int64 (or a uint64, if it is too
+// large for an int64).
+func parseInteger(_ context.Context, p *gval.Parser) (gval.Evaluable, error) {
+	text := p.TokenText()
+	if i, err := strconv.ParseInt(text, 10, 64); err == nil && i > maxExactInteger {
+		return p.Const(i), nil
+	}
+	if u, err := strconv.ParseUint(text, 10, 64); err == nil && u > maxExactInteger {
+		return p.Const(u), nil
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, err
+	}
+	return p.Const(f), nil
+}
+
+// negateNumber is the "-" prefix operator of the filter language,
+// which keeps the integers of parseInteger exact.
+func negateNumber(_ context.Context, v any) (any, error) {
+	switch val := v.(type) {
+	case int64:
+		return -val, nil
+	case uint64:
+		if val <= 1<<63 {
+			return -int64(val-1) - 1, nil
+		}
+	}
+	f, ok := toFloat(v)
+	if !ok {
+		return nil, fmt.Errorf("unexpected %v(%T) expected number", v, v)
+	}
+	return -f, nil
+}
+
+// equalOperator implements the equality operators;
+// it compares the operands with equal.
+func equalOperator(expected bool) func(a, b any) (any, error) {
+	return func(a, b any) (any, error) {
+		return equal(a, b) == expected, nil
+	}
+}
+
+// orderedOperator implements the ordering operators. A nil
+// operand (e.g. a nil nested struct) is not ordered, so the
+// comparison is false; if either operand is a date, both are
+// compared as dates (see toTimes), numbers are compared by
+// their values (see compareNumbers), and any other operands
+// are compared as strings, which is what gval does by default.
+func orderedOperator(fn func(c int) bool) func(a, b any) (any, error) {
+	return func(a, b any) (any, error) {
+		if a == nil || b == nil {
+			return false, nil
+		}
+		at, bt, ok, err := toTimes(a, b)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return fn(at.Compare(bt)), nil
+		}
+		if c, ok := compareNumbers(toNumber(a, b), toNumber(b, a)); ok {
+			return fn(c), nil
+		}
+		return fn(strings.Compare(fmt.Sprint(a), fmt.Sprint(b))), nil
+	}
+}
+
+// withinFunction reports whether its first argument is a date
+// within the duration given by its second argument (see
+// parseDuration) of the current time, either before or after it.
+func withinFunction(_ context.Context, args ...any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("within() expects exactly two arguments")
+	}
+	s, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("within() expects a duration as its second argument, got %T", args[1])
+	}
+	d, err := parseDuration(s)
+	if err != nil {
+		return nil, err
+	}
+	if args[0] == nil {
+		return false, nil
+	}
+	t, ok := toTime(args[0])
+	if !ok {
+		return nil, fmt.Errorf("within() expects a date as its first argument, got %T", args[0])
+	}
+	now := time.Now()
+
+	return !t.Before(now.Add(-d)) && !t.After(now.Add(d)), nil
+}
+
+// elementEvaluables are the compiled sub-expressions of the
+// quantifiers of an expression, keyed by their text; Compile
+// compiles them along with the expression, and passes them to
+// the quantifier functions in the context of its evaluation.
+type elementEvaluables map[string]gval.Evaluable
+
+type elementEvaluablesKey struct{}
+
+// compile compiles the sub-expressions of the
+// quantifiers of the given expression tree.
+func (e elementEvaluables) compile(expr Expr) error {
+	switch expr := expr.(type) {
+	case And:
+		for _, clause := range expr {
+			if err := e.compile(clause); err != nil {
+				return err
+			}
+		}
+	case Or:
+		for _, clause := range expr {
+			if err := e.compile(clause); err != nil {
+				return err
+			}
+		}
+	case Not:
+		return e.compile(expr.Expr)
+	case Quantifier:
+		expression := expr.Expr.Expression()
+		if _, ok := e[expression]; ok {
+			return nil
+		}
+		evaluable, err := language.NewEvaluable(expression)
+		if err != nil {
+			return err
+		}
+		e[expression] = evaluable
+		return e.compile(expr.Expr)
+	}
+
+	return nil
+}
+
+// elementEvaluable returns the compiled sub-expression of a
+// quantifier from the context of the evaluation; sub-expressions
+// that weren't compiled by Compile (e.g. in an expression that is
+// evaluated with MustEvaluate) are compiled on demand.
+func elementEvaluable(ctx context.Context, expression string) (gval.Evaluable, error) {
+	if evaluables, ok := ctx.Value(elementEvaluablesKey{}).(elementEvaluables); ok {
+		if evaluable, ok := evaluables[expression]; ok {
+			return evaluable, nil
+		}
+	}
+
+	return language.NewEvaluable(expression)
+}
+
+// quantifierFunction returns a gval function that evaluates the
+// sub-expression of a quantifier (its second argument) against
+// the elements of a list (its first argument); the function
+// returns the given result as soon as the sub-expression of an
+// element is equal to stopOn, and the opposite result otherwise.
+func quantifierFunction(name string, stopOn, result bool) func(context.Context, ...any) (any, error) {
+	return func(ctx context.Context, args ...any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() expects exactly two arguments", name)
+		}
+		list, ok := toList(args[0])
+		if !ok {
+			return nil, fmt.Errorf("%s() expects a list as its first argument, got %T", name, args[0])
+		}
+		expression, ok := args[1].(string)
+		if !ok {
+			return nil, fmt.Errorf("%s() expects an expression as its second argument, got %T", name, args[1])
+		}
+		evaluable, err := elementEvaluable(ctx, expression)
+		if err != nil {
+			return nil, err
+		}
+		for _, elem := range list {
+			matches, err := evaluable.EvalBool(ctx, map[string]any{elementVariable: elem})
+			if err != nil {
+				return nil, err
+			}
+			if matches == stopOn {
+				return result, nil
+			}
+		}
+
+		return !result, nil
+	}
+}
+
+// sizeOfFunction evaluates the sub-expression of the "size"
+// quantifier (its second argument) against the number of
+// elements of a list (its first argument); a null list
+// has no elements.
+func sizeOfFunction(ctx context.Context, args ...any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("sizeOf() expects exactly two arguments")
+	}
+	list, ok := toList(args[0])
+	if !ok {
+		return nil, fmt.Errorf("sizeOf() expects a list as its first argument, got %T", args[0])
+	}
+	expression, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("sizeOf() expects an expression as its second argument, got %T", args[1])
+	}
+	evaluable, err := elementEvaluable(ctx, expression)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluable.EvalBool(ctx, map[string]any{elementVariable: len(list)})
+}
+
+// isIn reports whether the first argument is equal
+// to any of the elements of the second argument.
+func isIn(_ context.Context, args ...any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("isIn() expects exactly two arguments")
+	}
+	list, ok := args[1].([]any)
+	if !ok {
+		return nil, fmt.Errorf("isIn() expects a list as its second argument, got %T", args[1])
+	}
+	for _, elem := range list {
+		if equal(args[0], elem) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// stringFunction adapts a string predicate into a gval function;
+// the function is false if the first argument is not a string.
+func stringFunction(name string, fn func(s, substr string) bool) func(context.Context, ...any) (any, error) {
+	return func(_ context.Context, args ...any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s() expects exactly two arguments", name)
+		}
+		substr, ok := toString(args[1])
+		if !ok {
+			return nil, fmt.Errorf("%s() expects a string as its second argument, got %T", name, args[1])
+		}
+		s, ok := toString(args[0])
+		if !ok {
+			return false, nil
+		}
+
+		return fn(s, substr), nil
+	}
+}
+
+// isNullFunction reports whether its argument is nil,
+// or a nil pointer, slice or map.
+func isNullFunction(_ context.Context, args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("isNull() expects exactly one argument")
+	}
+
+	return isNil(args[0]), nil
+}
+
+// normalizeFunction applies a Unicode normalization form (see
+// normalizationForms) to its first argument, if it is a string.
+func normalizeFunction(_ context.Context, args ...any) (any, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("normalize() expects exactly two arguments")
+	}
+	form, ok := args[1].(string)
+	if !ok || !normalizationForms[form] {
+		return nil, fmt.Errorf("normalize() expects a normalization form as its second argument, got %v", args[1])
+	}
+	s, ok := toString(args[0])
+	if !ok {
+		return args[0], nil
+	}
+
+	return normalize(s, form), nil
+}
+
+// foldFunction folds the case of its argument, if it is a string.
+func foldFunction(_ context.Context, args ...any) (any, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("fold() expects exactly one argument")
+	}
+	s, ok := toString(args[0])
+	if !ok {
+		return args[0], nil
+	}
+
+	return caseFold(s), nil
+}
+
+// normalize applies the given normalization form to s.
+func normalize(s, form string) string {
+	switch form {
+	case "NFC":
+		return norm.NFC.String(s)
+	case "NFD":
+		return norm.NFD.String(s)
+	case "NFKC":
+		return norm.NFKC.String(s)
+	case "NFKD":
+		return norm.NFKD.String(s)
+	case "unaccent":
+		decomposed := norm.NFKD.String(s)
+		unaccented := strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Mn, r) {
+				return -1
+			}
+			return r
+		}, decomposed)
+		return norm.NFC.String(unaccented)
+	}
+
+	return s
+}
+
+// caseFold returns the case-folded form of s, which can be
+// used for case-insensitive comparisons. A new Caser is
+// created for each call, because Casers are not safe for
+// concurrent use.
+func caseFold(s string) string {
+	return cases.Fold().String(s)
+}
+
+// equal compares two values the same way that the gval "=="
+// operator does; numbers of different types are equal if their
+// values are equal (see compareNumbers), and strings are compared
+// by value, regardless of their type. If either value is a date,
+// both are compared as dates.
+func equal(a, b any) bool {
+	if at, bt, ok, err := toTimes(a, b); ok {
+		return err == nil && at.Equal(bt)
+	}
+	if c, ok := compareNumbers(toNumber(a, b), toNumber(b, a)); ok {
+		return c == 0
+	}
+	if _, ok := toFloat(a); ok {
+		return false
+	}
+	if as, ok := toString(a); ok {
+		bs, ok := toString(b)
+		return ok && as == bs
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return value.IsNil()
+	}
+
+	return false
+}
+
+// toNumber converts v, if it is a json.Number (see numberOf), or
+// a string that is compared with a number, into a number, as gval
+// does; other values are returned unchanged.
+func toNumber(v, other any) any {
+	if n, ok := v.(json.Number); ok {
+		return numberOf(n)
+	}
+	s, ok := toString(v)
+	if !ok {
+		return v
+	}
+	if _, ok := toFloat(numberOf(other)); !ok {
+		return v
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return v
+}
+
+func toFloat(v any) (float64, bool) {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), true
+	}
+
+	return 0, false
+}
+
+// toList converts a slice or array into a list of its
+// elements; nil is an empty list.
+func toList(v any) ([]any, bool) {
+	if v == nil {
+		return nil, true
+	}
+	if list, ok := v.([]any); ok {
+		return list, true
+	}
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return nil, false
+	}
+	list := make([]any, value.Len())
+	for i := range list {
+		list[i] = value.Index(i).Interface()
+	}
+
+	return list, true
+}
+
+func toString(v any) (string, bool) {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.String {
+		return value.String(), true
+	}
+
+	return "", false
+}
@@ -1 +1,260 @@
 package filter
+
+import (
+	"fmt"
+	"go/format"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// filterInput is a filter type that is generated for a struct
+// type, by GenerateGraphQL and GenerateGo.
+type filterInput struct {
+	name   string
+	fields []inputField
+}
+
+// inputField is a field of a generated filter type; its type is the
+// name of either another generated filter type, "Operator", or
+// "Boolean" (for the operators that check whether a field is null).
+type inputField struct {
+	name   string
+	goName string
+	typ    string
+	list   bool
+}
+
+// GenerateGraphQL returns the GraphQL SDL of the input types that
+// mirror the StructFilter for type T, with the same JSON names as
+// the fields of T. For example, for a Movie struct, it returns
+// something like this:
+//
+//	input MovieFilter {
+//		title: Operator
+//		director: PersonFilter
+//		cast: PersonListFilter
+//		and: [MovieFilter!]
+//		or: [MovieFilter!]
+//		not: MovieFilter
+//	}
+//
+// along with the input types of the nested structs and lists of
+// structs, which also have the "exists" and "isNull" operators of
+// the struct or list itself. Nested structs that have the same name,
+// but are different types (e.g. in different packages), have filter
+// types with a numeric suffix (e.g. "Person2Filter"). The Operator
+// input type itself is not included; see GenerateGraphQLOperator.
+func GenerateGraphQL[T any]() string {
+	var sdl strings.Builder
+	for i, input := range filterInputs(reflect.TypeFor[T]()) {
+		if i > 0 {
+			sdl.WriteString("\n")
+		}
+		fmt.Fprintf(&sdl, "input %s {\n", input.name)
+		for _, field := range input.fields {
+			typ := field.typ
+			if field.list {
+				typ = "[" + typ + "!]"
+			}
+			fmt.Fprintf(&sdl, "  %s: %s\n", field.name, typ)
+		}
+		sdl.WriteString("}\n")
+	}
+
+	return sdl.String()
+}
+
+// GenerateGraphQLOperator returns the GraphQL SDL of the Operator
+// input type that the types of GenerateGraphQL refer to; the values
+// of the operators have the Any scalar, which gqlgen maps to `any`,
+// so the input type can be bound to filter.Operator.
+func GenerateGraphQLOperator() string {
+	var sdl strings.Builder
+	sdl.WriteString("scalar Any\n\ninput Operator {\n")
+	operatorType := reflect.TypeFor[Operator]()
+	for i := 0; i < operatorType.NumField(); i++ {
+		field := operatorType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		typ := "Any"
+		switch field.Type.Kind() {
+		case reflect.String:
+			typ = "String"
+		case reflect.Slice:
+			typ = "[Operator!]"
+		}
+		fmt.Fprintf(&sdl, "  %s: %s\n", name, typ)
+	}
+	sdl.WriteString("}\n")
+
+	return sdl.String()
+}
+
+// GenerateGo returns the (formatted) Go source of a file in the given
+// package, with the filter structs that correspond to the input types
+// of GenerateGraphQL; these are the same kind of structs as the
+// MovieFilter example in the package documentation, and they can be
+// used as the models of the input types in gqlgen.
+func GenerateGo[T any](pkg string) ([]byte, error) {
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by filter.GenerateGo; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&src, "package %s\n\n", pkg)
+	fmt.Fprintf(&src, "import \"github.com/tartale/go/pkg/filter\"\n")
+	for _, input := range filterInputs(reflect.TypeFor[T]()) {
+		fmt.Fprintf(&src, "\ntype %s struct {\n", input.name)
+		for _, field := range input.fields {
+			typ := "*" + field.typ
+			switch field.typ {
+			case "Operator":
+				typ = "*filter.Operator"
+			case "Boolean":
+				typ = "*bool"
+			}
+			if field.list {
+				typ = "[]" + typ
+			}
+			fmt.Fprintf(&src, "%s %s `json:\"%s,omitempty\"`\n", field.goName, typ, field.name)
+		}
+		src.WriteString("}\n")
+	}
+
+	return format.Source([]byte(src.String()))
+}
+
+// filterInputs returns the filter types that are generated for the
+// given struct type: the top-level filter type first, followed by
+// the filter types of its nested structs and lists, in the order
+// in which they are first used. The filter type of a nested struct
+// is named after the struct (see structNames), so it is generated
+// only once, no matter how many fields have that struct type.
+func filterInputs(t reflect.Type) []filterInput {
+	name := t.Name() + "Filter"
+	g := inputGenerator{generated: map[string]bool{name: true}, names: newStructNames(t)}
+	root := filterInput{name: name, fields: g.fields(schemaOf(t), t.Name())}
+	root.fields = append(root.fields,
+		inputField{name: "and", goName: "And", typ: name, list: true},
+		inputField{name: "or", goName: "Or", typ: name, list: true},
+		inputField{name: "not", goName: "Not", typ: name},
+	)
+
+	return append([]filterInput{root}, g.inputs...)
+}
+
+type inputGenerator struct {
+	inputs    []filterInput
+	generated map[string]bool
+	names     *structNames
+}
+
+// fields returns the fields of the filter type
+// that mirrors the fields of the given schema.
+func (g *inputGenerator) fields(schema *schemaField, parentName string) []inputField {
+	var fields []inputField
+	for _, field := range schema.fields {
+		fields = append(fields, inputField{
+			name:   field.name,
+			goName: field.goName,
+			typ:    g.fieldType(field, parentName+field.goName),
+		})
+	}
+
+	return fields
+}
+
+// fieldType returns the name of the filter type of the given field,
+// generating it if necessary. Primitive fields, and lists of primitive
+// fields, are filtered with an Operator (which has the quantifiers);
+// a nested struct has a filter type of its own, and a list of structs
+// has a filter type with quantifiers of the filter type of the struct;
+// like an Operator, these filter types have the operators that check
+// whether the field is null, and an "and" list of themselves.
+func (g *inputGenerator) fieldType(field *schemaField, fieldName string) string {
+	if field.elem != nil {
+		elemType := g.fieldType(field.elem, fieldName+"Elem")
+		if elemType == "Operator" {
+			return elemType
+		}
+		name := strings.TrimSuffix(elemType, "Filter") + "ListFilter"
+		g.generate(name, func() []inputField {
+			return []inputField{
+				{name: "any", goName: "Any", typ: elemType},
+				{name: "all", goName: "All", typ: elemType},
+				{name: "none", goName: "None", typ: elemType},
+				{name: "size", goName: "Size", typ: "Operator"},
+				{name: "exists", goName: "Exists", typ: "Boolean"},
+				{name: "isNull", goName: "IsNull", typ: "Boolean"},
+				{name: "and", goName: "And", typ: name, list: true},
+			}
+		})
+		return name
+	}
+	if len(field.fields) == 0 {
+		return "Operator"
+	}
+	typeName := g.names.of(field, fieldName)
+	name := typeName + "Filter"
+	g.generate(name, func() []inputField {
+		return append(g.fields(field, typeName),
+			inputField{name: "exists", goName: "Exists", typ: "Boolean"},
+			inputField{name: "isNull", goName: "IsNull", typ: "Boolean"},
+			inputField{name: "and", goName: "And", typ: name, list: true},
+		)
+	})
+
+	return name
+}
+
+// generate adds the filter type with the given name
+// and fields, unless it has already been generated.
+// The fields are generated after the filter type is added, so that
+// the filter types of its own fields follow it.
+func (g *inputGenerator) generate(name string, fields func() []inputField) {
+	if g.generated[name] {
+		return
+	}
+	g.generated[name] = true
+	i := len(g.inputs)
+	g.inputs = append(g.inputs, filterInput{name: name})
+	inputFields := fields()
+	g.inputs[i].fields = inputFields
+}
+
+// structNames names the nested structs of a struct type, which the
+// generated types for the structs are named after. Each struct type
+// has a single name, and different types have different names: a
+// struct that has the same name as another one (e.g. a struct of
+// another package) is named with a numeric suffix (e.g. "Person2").
+// Anonymous (and generic) structs are named after the field.
+type structNames struct {
+	byType map[reflect.Type]string
+	taken  map[string]bool
+}
+
+// newStructNames returns the names of the nested
+// structs of the given (top-level) struct type.
+func newStructNames(root reflect.Type) *structNames {
+	return &structNames{
+		byType: map[reflect.Type]string{root: root.Name()},
+		taken:  map[string]bool{root.Name(): true},
+	}
+}
+
+// of returns the name of the nested struct of the given field.
+func (n *structNames) of(field *schemaField, fieldName string) string {
+	t := nestedStruct(field.typ)
+	if name, ok := n.byType[t]; ok {
+		return name
+	}
+	base := t.Name()
+	if base == "" || strings.ContainsAny(base, "[]") {
+		base = fieldName
+	}
+	name := base
+	for i := 2; n.taken[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	n.byType[t] = name
+	n.taken[name] = true
+
+	return name
+}
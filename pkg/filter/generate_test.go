@@ -0,0 +1,201 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const expectedMovieFilterSDL = `input MovieFilter {
+  kind: Operator
+  title: Operator
+  description: Operator
+  movieYear: Operator
+  tagline: Operator
+  director: PersonFilter
+  studio: StudioFilter
+  genres: Operator
+  cast: PersonListFilter
+  releaseDate: Operator
+  updatedAt: Operator
+  and: [MovieFilter!]
+  or: [MovieFilter!]
+  not: MovieFilter
+}
+
+input PersonFilter {
+  name: Operator
+  birthYear: Operator
+  exists: Boolean
+  isNull: Boolean
+  and: [PersonFilter!]
+}
+
+input StudioFilter {
+  name: Operator
+  country: Operator
+  exists: Boolean
+  isNull: Boolean
+  and: [StudioFilter!]
+}
+
+input PersonListFilter {
+  any: PersonFilter
+  all: PersonFilter
+  none: PersonFilter
+  size: Operator
+  exists: Boolean
+  isNull: Boolean
+  and: [PersonListFilter!]
+}
+`
+
+const expectedMovieFilterGo = "// Code generated by filter.GenerateGo; DO NOT EDIT.\n" + `
+package models
+
+import "github.com/tartale/go/pkg/filter"
+
+type MovieFilter struct {
+	Kind        *filter.Operator  ` + "`json:\"kind,omitempty\"`" + `
+	Title       *filter.Operator  ` + "`json:\"title,omitempty\"`" + `
+	Description *filter.Operator  ` + "`json:\"description,omitempty\"`" + `
+	MovieYear   *filter.Operator  ` + "`json:\"movieYear,omitempty\"`" + `
+	Tagline     *filter.Operator  ` + "`json:\"tagline,omitempty\"`" + `
+	Director    *PersonFilter     ` + "`json:\"director,omitempty\"`" + `
+	Studio      *StudioFilter     ` + "`json:\"studio,omitempty\"`" + `
+	Genres      *filter.Operator  ` + "`json:\"genres,omitempty\"`" + `
+	Cast        *PersonListFilter ` + "`json:\"cast,omitempty\"`" + `
+	ReleaseDate *filter.Operator  ` + "`json:\"releaseDate,omitempty\"`" + `
+	UpdatedAt   *filter.Operator  ` + "`json:\"updatedAt,omitempty\"`" + `
+	And         []*MovieFilter    ` + "`json:\"and,omitempty\"`" + `
+	Or          []*MovieFilter    ` + "`json:\"or,omitempty\"`" + `
+	Not         *MovieFilter      ` + "`json:\"not,omitempty\"`" + `
+}
+
+type PersonFilter struct {
+	Name      *filter.Operator ` + "`json:\"name,omitempty\"`" + `
+	BirthYear *filter.Operator ` + "`json:\"birthYear,omitempty\"`" + `
+	Exists    *bool            ` + "`json:\"exists,omitempty\"`" + `
+	IsNull    *bool            ` + "`json:\"isNull,omitempty\"`" + `
+	And       []*PersonFilter  ` + "`json:\"and,omitempty\"`" + `
+}
+
+type StudioFilter struct {
+	Name    *filter.Operator ` + "`json:\"name,omitempty\"`" + `
+	Country *filter.Operator ` + "`json:\"country,omitempty\"`" + `
+	Exists  *bool            ` + "`json:\"exists,omitempty\"`" + `
+	IsNull  *bool            ` + "`json:\"isNull,omitempty\"`" + `
+	And     []*StudioFilter  ` + "`json:\"and,omitempty\"`" + `
+}
+
+type PersonListFilter struct {
+	Any    *PersonFilter       ` + "`json:\"any,omitempty\"`" + `
+	All    *PersonFilter       ` + "`json:\"all,omitempty\"`" + `
+	None   *PersonFilter       ` + "`json:\"none,omitempty\"`" + `
+	Size   *filter.Operator    ` + "`json:\"size,omitempty\"`" + `
+	Exists *bool               ` + "`json:\"exists,omitempty\"`" + `
+	IsNull *bool               ` + "`json:\"isNull,omitempty\"`" + `
+	And    []*PersonListFilter ` + "`json:\"and,omitempty\"`" + `
+}
+`
+
+func TestGenerateGraphQL(t *testing.T) {
+	assert.Equal(t, expectedMovieFilterSDL, GenerateGraphQL[Movie]())
+}
+
+func TestGenerateGraphQL_ValidSchema(t *testing.T) {
+	schema, err := gqlparser.LoadSchema(
+		&ast.Source{Name: "operator.graphql", Input: GenerateGraphQLOperator()},
+		&ast.Source{Name: "movie.graphql", Input: GenerateGraphQL[Movie]()},
+		&ast.Source{Name: "query.graphql", Input: `type Query { movies(filter: [MovieFilter!]): [String!] }`},
+	)
+
+	require.Nil(t, err)
+	operator := schema.Types["Operator"]
+	require.NotNil(t, operator)
+	assert.NotNil(t, operator.Fields.ForName("startsWith"))
+	assert.Equal(t, "String", operator.Fields.ForName("normalize").Type.Name())
+	assert.Equal(t, "PersonFilter", schema.Types["PersonListFilter"].Fields.ForName("any").Type.Name())
+	assert.Equal(t, "Operator", operator.Fields.ForName("and").Type.Elem.Name())
+}
+
+func TestGenerateGo(t *testing.T) {
+	src, err := GenerateGo[Movie]("models")
+
+	require.NoError(t, err)
+	assert.Equal(t, expectedMovieFilterGo, string(src))
+}
+
+func TestGenerateGraphQL_AnonymousAndRecursiveStructs(t *testing.T) {
+	type Node struct {
+		Name string `json:"name"`
+		// As in a StructFilter, the fields of
+		// a recursive struct can't be filtered.
+		Children []Node `json:"children"`
+		Meta     struct {
+			Owner string `json:"owner"`
+		} `json:"meta"`
+	}
+
+	assert.Equal(t, `input NodeFilter {
+  name: Operator
+  children: Operator
+  meta: NodeMetaFilter
+  and: [NodeFilter!]
+  or: [NodeFilter!]
+  not: NodeFilter
+}
+
+input NodeMetaFilter {
+  owner: Operator
+  exists: Boolean
+  isNull: Boolean
+  and: [NodeMetaFilter!]
+}
+`, GenerateGraphQL[Node]())
+}
+
+func TestGenerateGraphQL_StructsWithTheSameName(t *testing.T) {
+	type director = Person
+	type Person struct {
+		Nickname string `json:"nickname"`
+	}
+	type Credits struct {
+		Director director `json:"director"`
+		Writer   Person   `json:"writer"`
+		Producer *Person  `json:"producer"`
+	}
+
+	assert.Equal(t, `input CreditsFilter {
+  director: PersonFilter
+  writer: Person2Filter
+  producer: Person2Filter
+  and: [CreditsFilter!]
+  or: [CreditsFilter!]
+  not: CreditsFilter
+}
+
+input PersonFilter {
+  name: Operator
+  birthYear: Operator
+  exists: Boolean
+  isNull: Boolean
+  and: [PersonFilter!]
+}
+
+input Person2Filter {
+  nickname: Operator
+  exists: Boolean
+  isNull: Boolean
+  and: [Person2Filter!]
+}
+`, GenerateGraphQL[Credits]())
+
+	defs := JSONSchema[Credits]()["$defs"].(map[string]any)
+	require.Contains(t, defs, "Person2Filter")
+	assert.Contains(t, defs["Person2Filter"].(map[string]any)["properties"], "nickname")
+	assert.Contains(t, defs["CreditsFilter"].(map[string]any)["properties"], "writer.nickname")
+}
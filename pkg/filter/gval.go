@@ -1,13 +1,62 @@
 package filter
 
-import "github.com/PaesslerAG/gval"
+import (
+	"context"
+
+	"github.com/PaesslerAG/gval"
+)
+
+// language is the gval language that the
+// expressions generated by this package are
+// compiled with. It is initialized by init,
+// because the functions of the language compile
+// the sub-expressions of quantifiers with it.
+var language gval.Language
+
+func init() {
+	language = gval.Full(functions)
+}
 
 // MustEvaluate is a wrapper around the gval.Evaluate function
-// that panics if an error is returned.
+// that panics if an error is returned. The expression is evaluated
+// with the functions that the operators of filter.Operator rely on.
 func MustEvaluate(expression string, parameter any, opts ...gval.Language) any {
-	result, err := gval.Evaluate(expression, parameter, opts...)
+	l := gval.NewLanguage(append([]gval.Language{language}, opts...)...)
+	result, err := l.Evaluate(expression, parameter)
 	if err != nil {
 		panic(err)
 	}
 	return result
 }
+
+// Compile turns an expression tree into a gval.Evaluable
+// that can be evaluated repeatedly against different values,
+// without having to re-parse the expression each time. The
+// sub-expressions of its quantifiers are compiled along with it.
+func Compile(expr Expr) (gval.Evaluable, error) {
+	evaluable, err := language.NewEvaluable(expr.Expression())
+	if err != nil {
+		return nil, err
+	}
+	elements := elementEvaluables{}
+	if err := elements.compile(expr); err != nil {
+		return nil, err
+	}
+	if len(elements) == 0 {
+		return evaluable, nil
+	}
+
+	return func(ctx context.Context, parameter any) (any, error) {
+		return evaluable(context.WithValue(ctx, elementEvaluablesKey{}, elements), parameter)
+	}, nil
+}
+
+// MustCompile is a convenience function that wraps Compile,
+// but panics if an error occurs.
+func MustCompile(expr Expr) gval.Evaluable {
+	evaluable, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return evaluable
+}
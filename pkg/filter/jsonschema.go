@@ -0,0 +1,346 @@
+package filter
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect
+// of the documents returned by JSONSchema.
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// leafKind is the kind of a field that is filtered
+// with an operator object, which determines the operators
+// that apply to it, and the types of their values.
+type leafKind string
+
+const (
+	stringLeaf  leafKind = "String"
+	integerLeaf leafKind = "Integer"
+	numberLeaf  leafKind = "Number"
+	booleanLeaf leafKind = "Boolean"
+	dateLeaf    leafKind = "Date"
+	anyLeaf     leafKind = "Any"
+)
+
+// JSONSchema returns a JSON Schema document that validates the
+// JSON representation of a StructFilter for type T: either a single
+// filter object, or a list of them. The operators of each field are
+// the ones that apply to the kind of the field (e.g. a numeric field
+// has no "contains"), and that its filter tag allows (see
+// NewStructFilterE), and their values are typed accordingly; nested
+// structs, lists, dotted paths and the "and", "or" and "not" clauses
+// are all described. The filter types are in "$defs"; for example,
+// the filter object for a Movie struct is "#/$defs/MovieFilter".
+//
+// The document is a map, so it can be marshalled as-is, or combined
+// with other documents; see also OpenAPISchemas.
+func JSONSchema[T any]() map[string]any {
+	g := newSchemaGenerator("#/$defs/")
+	root := g.root(reflect.TypeFor[T]())
+
+	return map[string]any{
+		"$schema": JSONSchemaDraft,
+		"oneOf": []any{
+			g.ref(root),
+			map[string]any{"type": "array", "items": g.ref(root)},
+		},
+		"$defs": g.defs,
+	}
+}
+
+// OpenAPISchemas returns the same definitions as JSONSchema, keyed by
+// their names, and referring to each other as "#/components/schemas/...",
+// so that they can be added to the components of an OpenAPI (3.1)
+// document. The name of the filter object for type T is the name of T,
+// followed by "Filter".
+func OpenAPISchemas[T any]() map[string]any {
+	g := newSchemaGenerator("#/components/schemas/")
+	g.root(reflect.TypeFor[T]())
+
+	return g.defs
+}
+
+// schemaGenerator generates the definitions of the filter types of a
+// struct type. Its filter types have the same names as the ones of
+// GenerateGraphQL, except that the operator objects are typed, and
+// named after the kind of their field (e.g. "StringOperator"). The
+// filter types of fields with a filter tag are named after the field
+// (e.g. "AccountEmailStringOperator"), since they have fewer operators.
+type schemaGenerator struct {
+	refPrefix string
+	defs      map[string]any
+	names     *structNames
+}
+
+func newSchemaGenerator(refPrefix string) *schemaGenerator {
+	return &schemaGenerator{refPrefix: refPrefix, defs: map[string]any{}}
+}
+
+func (g *schemaGenerator) ref(name string) map[string]any {
+	return map[string]any{"$ref": g.refPrefix + name}
+}
+
+// listOf returns the schema of a list of the given definition.
+func (g *schemaGenerator) listOf(name string) map[string]any {
+	return map[string]any{"type": "array", "items": g.ref(name)}
+}
+
+// root generates the definition of the top-level
+// filter object of the given type, and returns its name.
+func (g *schemaGenerator) root(t reflect.Type) string {
+	name := t.Name() + "Filter"
+	g.names = newStructNames(t)
+	def := map[string]any{"type": "object", "additionalProperties": false}
+	g.defs[name] = def
+	properties := g.properties(schemaOf(t), t.Name())
+	properties["and"] = g.listOf(name)
+	properties["or"] = g.listOf(name)
+	properties["not"] = g.ref(name)
+	def["properties"] = properties
+
+	return name
+}
+
+// properties returns the schemas of the fields of the given schema,
+// keyed by their JSON names, and by their dotted paths (e.g.
+// "director.name").
+func (g *schemaGenerator) properties(schema *schemaField, parentName string) map[string]any {
+	properties := map[string]any{}
+	for _, field := range schema.fields {
+		properties[field.name] = g.ref(g.fieldSchema(field, parentName+field.goName))
+		if len(field.fields) == 0 {
+			continue
+		}
+		for path, nested := range g.properties(field, g.names.of(field, parentName+field.goName)) {
+			properties[field.name+"."+path] = nested
+		}
+	}
+
+	return properties
+}
+
+// fieldSchema generates the definition of the filter type of the
+// given field (if it hasn't already been generated), and returns
+// its name. As with fieldFilterType, the filter objects of nested
+// structs and lists also accept the operators that check whether
+// the field is null; like operator objects, they also accept an
+// "and" list of themselves.
+func (g *schemaGenerator) fieldSchema(field *schemaField, fieldName string) string {
+	if field.elem != nil {
+		elemName := g.fieldSchema(field.elem, fieldName+"Elem")
+		name := listName(elemName)
+		g.generate(name, func() map[string]any {
+			properties := nullOperators()
+			properties["any"] = g.ref(elemName)
+			properties["all"] = g.ref(elemName)
+			properties["none"] = g.ref(elemName)
+			properties["size"] = g.ref(g.leafSchema(integerLeaf, nil, ""))
+			properties["and"] = g.listOf(name)
+			return field.allowedProperties(properties)
+		})
+		return name
+	}
+	if len(field.fields) == 0 {
+		return g.leafSchema(leafKindOf(field.typ), field, fieldName)
+	}
+	typeName := g.names.of(field, fieldName)
+	name := typeName + "Filter"
+	if field.operators != nil {
+		name = fieldName + name
+	}
+	g.generate(name, func() map[string]any {
+		properties := g.properties(field, typeName)
+		for operator, schema := range nullOperators() {
+			properties[operator] = schema
+		}
+		properties["and"] = g.listOf(name)
+		return field.allowedProperties(properties)
+	})
+
+	return name
+}
+
+// leafSchema generates the definition of the operator object of
+// the given kind of field; if the field has a filter tag, the
+// definition only has the operators that it allows, and is named
+// after the field.
+func (g *schemaGenerator) leafSchema(kind leafKind, field *schemaField, fieldName string) string {
+	name := string(kind) + "Operator"
+	if field != nil && field.operators != nil {
+		name = fieldName + name
+	}
+	g.generate(name, func() map[string]any {
+		properties := map[string]any{}
+		for operator, op := range operators {
+			if schema := operatorValueSchema(op.value, kind); schema != nil {
+				properties[operator] = schema
+			}
+		}
+		if kind == stringLeaf || kind == anyLeaf {
+			properties["normalize"] = map[string]any{"enum": slices.Sorted(maps.Keys(normalizationForms))}
+		}
+		properties["and"] = g.listOf(name)
+		return field.allowedProperties(properties)
+	})
+
+	return name
+}
+
+// allowedProperties removes the operators (and quantifiers)
+// that the filter tag of the field doesn't allow from the given
+// properties of its filter type; as in the parser, the "and"
+// and "normalize" keys, and nested fields, are always allowed.
+func (f *schemaField) allowedProperties(properties map[string]any) map[string]any {
+	for key := range properties {
+		_, isOperator := operators[key]
+		_, isQuantifier := quantifiers[key]
+		if (isOperator || isQuantifier) && !f.allows(key) {
+			delete(properties, key)
+		}
+	}
+
+	return properties
+}
+
+// generate adds the definition of an object with the given
+// properties, unless it has already been generated.
+func (g *schemaGenerator) generate(name string, properties func() map[string]any) {
+	if _, ok := g.defs[name]; ok {
+		return
+	}
+	def := map[string]any{"type": "object", "additionalProperties": false}
+	g.defs[name] = def
+	def["properties"] = properties()
+}
+
+// nullOperators returns the schemas of the operators
+// that check whether a field is null.
+func nullOperators() map[string]any {
+	return map[string]any{
+		"exists": map[string]any{"type": "boolean"},
+		"isNull": map[string]any{"type": "boolean"},
+	}
+}
+
+// listName returns the name of the filter type of a list,
+// given the name of the filter type of its elements (e.g.
+// "PersonListFilter" for "PersonFilter").
+func listName(elemName string) string {
+	for _, suffix := range []string{"Filter", "Operator"} {
+		if base, ok := strings.CutSuffix(elemName, suffix); ok {
+			return base + "List" + suffix
+		}
+	}
+	return elemName + "List"
+}
+
+func (k leafKind) String() string {
+	if k == anyLeaf {
+		return "value"
+	}
+	return strings.ToLower(string(k))
+}
+
+// accepts reports whether a single literal (as decoded by
+// the parser) can be compared with a field of the kind.
+func (k leafKind) accepts(v any) bool {
+	switch k {
+	case stringLeaf, dateLeaf:
+		_, ok := v.(string)
+		return ok
+	case integerLeaf:
+		n, ok := v.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case numberLeaf:
+		_, ok := v.(json.Number)
+		return ok
+	case booleanLeaf:
+		_, ok := v.(bool)
+		return ok
+	}
+	return true
+}
+
+// leafKindOf returns the kind of a field of the given type.
+func leafKindOf(t reflect.Type) leafKind {
+	if isTime(t) {
+		return dateLeaf
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return stringLeaf
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return integerLeaf
+	case reflect.Float32, reflect.Float64:
+		return numberLeaf
+	case reflect.Bool:
+		return booleanLeaf
+	}
+	return anyLeaf
+}
+
+// scalarSchema returns the schema of a single
+// value of the given kind of field.
+func scalarSchema(kind leafKind) map[string]any {
+	switch kind {
+	case stringLeaf, dateLeaf:
+		return map[string]any{"type": "string"}
+	case integerLeaf:
+		return map[string]any{"type": "integer"}
+	case numberLeaf:
+		return map[string]any{"type": "number"}
+	case booleanLeaf:
+		return map[string]any{"type": "boolean"}
+	}
+	return map[string]any{"type": []any{"string", "number", "boolean"}}
+}
+
+// operatorValueSchema returns the schema of the value of an operator
+// that accepts the given kind of value, when it is applied to the given
+// kind of field; if the operator doesn't apply to that kind of field,
+// the result is nil.
+func operatorValueSchema(value valueKind, kind leafKind) map[string]any {
+	switch value {
+	case boolValue:
+		return map[string]any{"type": "boolean"}
+	case customValue:
+		return map[string]any{}
+	case timeValue, durationValue:
+		if kind != dateLeaf {
+			return nil
+		}
+		return map[string]any{"type": "string"}
+	case stringValue:
+		if kind != stringLeaf && kind != anyLeaf {
+			return nil
+		}
+		return map[string]any{"type": "string"}
+	case orderedValue:
+		if kind == booleanLeaf {
+			return nil
+		}
+		return scalarSchema(kind)
+	case listValue:
+		return map[string]any{"type": "array", "items": scalarSchema(kind)}
+	case rangeValue:
+		if kind == booleanLeaf {
+			return nil
+		}
+		return map[string]any{"type": "array", "items": scalarSchema(kind), "minItems": 2, "maxItems": 2}
+	}
+
+	return scalarSchema(kind)
+}
@@ -0,0 +1,168 @@
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONSchema(t *testing.T) {
+	schema := JSONSchema[Movie]()
+
+	assert.Equal(t, JSONSchemaDraft, schema["$schema"])
+	assert.Equal(t, []any{
+		map[string]any{"$ref": "#/$defs/MovieFilter"},
+		map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/MovieFilter"}},
+	}, schema["oneOf"])
+
+	defs := schema["$defs"].(map[string]any)
+	assert.ElementsMatch(t, []string{
+		"MovieFilter", "PersonFilter", "StudioFilter", "PersonListFilter",
+		"StringOperator", "IntegerOperator", "DateOperator", "StringListOperator",
+	}, slices.Collect(maps.Keys(defs)))
+
+	schemaJson, err := json.Marshal(defs["MovieFilter"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"kind": {"$ref": "#/$defs/StringOperator"},
+			"title": {"$ref": "#/$defs/StringOperator"},
+			"description": {"$ref": "#/$defs/StringOperator"},
+			"movieYear": {"$ref": "#/$defs/IntegerOperator"},
+			"tagline": {"$ref": "#/$defs/StringOperator"},
+			"director": {"$ref": "#/$defs/PersonFilter"},
+			"director.name": {"$ref": "#/$defs/StringOperator"},
+			"director.birthYear": {"$ref": "#/$defs/IntegerOperator"},
+			"studio": {"$ref": "#/$defs/StudioFilter"},
+			"studio.name": {"$ref": "#/$defs/StringOperator"},
+			"studio.country": {"$ref": "#/$defs/StringOperator"},
+			"genres": {"$ref": "#/$defs/StringListOperator"},
+			"cast": {"$ref": "#/$defs/PersonListFilter"},
+			"releaseDate": {"$ref": "#/$defs/DateOperator"},
+			"updatedAt": {"$ref": "#/$defs/DateOperator"},
+			"and": {"type": "array", "items": {"$ref": "#/$defs/MovieFilter"}},
+			"or": {"type": "array", "items": {"$ref": "#/$defs/MovieFilter"}},
+			"not": {"$ref": "#/$defs/MovieFilter"}
+		}
+	}`, string(schemaJson))
+
+	schemaJson, err = json.Marshal(defs["PersonListFilter"])
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"additionalProperties": false,
+		"properties": {
+			"any": {"$ref": "#/$defs/PersonFilter"},
+			"all": {"$ref": "#/$defs/PersonFilter"},
+			"none": {"$ref": "#/$defs/PersonFilter"},
+			"size": {"$ref": "#/$defs/IntegerOperator"},
+			"exists": {"type": "boolean"},
+			"isNull": {"type": "boolean"},
+			"and": {"type": "array", "items": {"$ref": "#/$defs/PersonListFilter"}}
+		}
+	}`, string(schemaJson))
+}
+
+func TestJSONSchema_OperatorsByKind(t *testing.T) {
+	type Sample struct {
+		Name    string   `json:"name"`
+		Count   int      `json:"count"`
+		Score   float64  `json:"score"`
+		Enabled bool     `json:"enabled"`
+		Extra   any      `json:"extra"`
+		Ratings []*int64 `json:"ratings"`
+	}
+
+	defs := JSONSchema[Sample]()["$defs"].(map[string]any)
+	operatorsOf := func(name string) map[string]any {
+		require.Contains(t, defs, name)
+		return defs[name].(map[string]any)["properties"].(map[string]any)
+	}
+
+	stringOperators := operatorsOf("StringOperator")
+	assert.Equal(t, map[string]any{"type": "string"}, stringOperators["icontains"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, stringOperators["in"])
+	assert.Equal(t, map[string]any{"enum": []string{"NFC", "NFD", "NFKC", "NFKD", "unaccent"}}, stringOperators["normalize"])
+	assert.NotContains(t, stringOperators, "before")
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/StringOperator"}}, stringOperators["and"])
+
+	integerOperators := operatorsOf("IntegerOperator")
+	assert.Equal(t, map[string]any{"type": "integer"}, integerOperators["gte"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "integer"}, "minItems": 2, "maxItems": 2}, integerOperators["between"])
+	assert.NotContains(t, integerOperators, "contains")
+	assert.NotContains(t, integerOperators, "normalize")
+
+	assert.Equal(t, map[string]any{"type": "number"}, operatorsOf("NumberOperator")["lt"])
+
+	booleanOperators := operatorsOf("BooleanOperator")
+	assert.Equal(t, map[string]any{"type": "boolean"}, booleanOperators["eq"])
+	assert.NotContains(t, booleanOperators, "lt")
+	assert.NotContains(t, booleanOperators, "between")
+
+	assert.Equal(t, map[string]any{"type": []any{"string", "number", "boolean"}}, operatorsOf("AnyOperator")["eq"])
+	assert.Equal(t, map[string]any{"$ref": "#/$defs/IntegerOperator"}, operatorsOf("IntegerListOperator")["any"])
+}
+
+func TestJSONSchema_FilterTags(t *testing.T) {
+	defs := JSONSchema[Account]()["$defs"].(map[string]any)
+	propertiesOf := func(ref any) map[string]any {
+		name := strings.TrimPrefix(ref.(map[string]any)["$ref"].(string), "#/$defs/")
+		require.Contains(t, defs, name)
+		return defs[name].(map[string]any)["properties"].(map[string]any)
+	}
+	fields := propertiesOf(map[string]any{"$ref": "#/$defs/AccountFilter"})
+	require.NotContains(t, fields, "password")
+
+	email := propertiesOf(fields["email"])
+	assert.ElementsMatch(t, []string{"eq", "in", "normalize", "and"}, slices.Collect(maps.Keys(email)))
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/$defs/AccountEmailStringOperator"}}, email["and"])
+	assert.Contains(t, propertiesOf(fields["name"]), "contains")
+
+	// The schema has the operators of the kind of each field
+	// (see TestJSONSchema_OperatorsByKind) that the parser accepts.
+	roles := propertiesOf(fields["roles"])
+	stringOperators := propertiesOf(map[string]any{"$ref": "#/$defs/StringOperator"})
+	listOperators := map[string]any{"exists": nil, "isNull": nil, "any": nil, "all": nil, "none": nil, "size": nil, "and": nil}
+	objects := []struct {
+		format             string
+		properties, ofKind map[string]any
+	}{
+		{`{"email": {"%s": null}}`, email, stringOperators},
+		{`{"name": {"%s": null}}`, propertiesOf(fields["name"]), stringOperators},
+		{`{"roles": {"%s": null}}`, roles, listOperators},
+		{`{"roles": {"any": {"%s": null}}}`, propertiesOf(roles["any"]), stringOperators},
+	}
+	for _, object := range objects {
+		for operator := range object.ofKind {
+			if operator == "and" || operator == "normalize" {
+				continue
+			}
+			filterJson := fmt.Sprintf(object.format, operator)
+			_, err := NewStructFilterE[Account](filterJson)
+
+			_, ok := object.properties[operator]
+			assert.Equal(t, !errors.Is(err, ErrOperatorNotAllowed), ok, filterJson)
+		}
+		for operator := range object.properties {
+			assert.Contains(t, object.ofKind, operator)
+		}
+	}
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	schemas := OpenAPISchemas[Movie]()
+
+	require.Contains(t, schemas, "MovieFilter")
+	properties := schemas["MovieFilter"].(map[string]any)["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"$ref": "#/components/schemas/PersonFilter"}, properties["director"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/MovieFilter"}}, properties["or"])
+}
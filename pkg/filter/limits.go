@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"encoding/json"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Limits restricts the complexity of the filters that this package
+// accepts, since filters usually come from untrusted clients; a limit
+// of zero means that there is no limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of the JSON
+	// representation of a filter (e.g. `[{"title": {"eq": "x"}}]`
+	// has a depth of 3).
+	MaxDepth int
+	// MaxClauses is the maximum number of comparisons (and
+	// quantifiers) in a filter.
+	MaxClauses int
+	// MaxPatternLength is the maximum length of the regular
+	// expressions of the "matches" and "imatches" operators.
+	MaxPatternLength int
+}
+
+// DefaultLimits are the limits of the filters that are parsed by this
+// package; they can be changed before any filters are parsed, such as
+// in an init function. Filters that exceed them are rejected with an
+// ErrFilterTooComplex.
+var DefaultLimits = Limits{
+	MaxDepth:         32,
+	MaxClauses:       256,
+	MaxPatternLength: 1024,
+}
+
+// checkDepth checks the nesting depth of the filter,
+// after the given token has been read.
+func (p *parser) checkDepth(tok any) error {
+	switch tok {
+	case json.Delim('['), json.Delim('{'):
+		p.depth++
+	case json.Delim(']'), json.Delim('}'):
+		p.depth--
+	}
+	if max := DefaultLimits.MaxDepth; max > 0 && p.depth > max {
+		return p.errorf(ErrFilterTooComplex, "the filter is nested more than %d levels deep", max)
+	}
+
+	return nil
+}
+
+// countClause counts a comparison (or a quantifier)
+// of the filter, and checks the number of clauses.
+func (p *parser) countClause() error {
+	p.clauses++
+	if max := DefaultLimits.MaxClauses; max > 0 && p.clauses > max {
+		return p.errorf(ErrFilterTooComplex, "the filter has more than %d clauses", max)
+	}
+
+	return nil
+}
+
+// checkPattern checks that the regular expression of a "matches"
+// or "imatches" operator compiles, that it is not too long, and that
+// it doesn't have nested repetitions (e.g. "(a+)+"). Patterns are
+// matched in linear time by Go, but not by the regular expression
+// engines of some databases (see ToSQL), in which nested repetitions
+// can take exponential time.
+func (p *parser) checkPattern(pattern string) error {
+	if max := DefaultLimits.MaxPatternLength; max > 0 && len(pattern) > max {
+		return p.errorf(ErrFilterTooComplex, "the pattern is longer than %d characters", max)
+	}
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return p.errorf(ErrInvalidPattern, "%s", err)
+	}
+	if nestedRepetition(re, false) {
+		return p.errorf(ErrInvalidPattern, "nested repetitions are not allowed")
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return p.errorf(ErrInvalidPattern, "%s", err)
+	}
+
+	return nil
+}
+
+// nestedRepetition reports whether the given regular expression has a
+// repetition of a variable number of occurrences (e.g. "a+" or "a{1,5}",
+// but not "a?" or "a{2}") within another one.
+func nestedRepetition(re *syntax.Regexp, repeated bool) bool {
+	repeats := false
+	switch re.Op {
+	case syntax.OpStar, syntax.OpPlus:
+		repeats = true
+	case syntax.OpRepeat:
+		repeats = re.Max == -1 || (re.Max > re.Min && re.Max > 1)
+	}
+	if repeats && repeated {
+		return true
+	}
+	for _, sub := range re.Sub {
+		if nestedRepetition(sub, repeated || repeats) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowedOperators parses the filter tag of a field, which restricts
+// the operators that can be applied to the field (e.g. `filter:"eq,in"`);
+// a field without a filter tag allows every operator.
+func allowedOperators(tag string) map[string]bool {
+	if tag == "" {
+		return nil
+	}
+	allowed := map[string]bool{}
+	for _, operator := range strings.Split(tag, ",") {
+		allowed[strings.TrimSpace(operator)] = true
+	}
+
+	return allowed
+}
+
+// allows reports whether the given operator (or
+// quantifier) can be applied to the field.
+func (f *schemaField) allows(operator string) bool {
+	return f == nil || f.operators == nil || f.operators[operator]
+}
+
+// checkOperator checks that the given operator
+// can be applied to the given field.
+func (p *parser) checkOperator(operator string, field *schemaField) error {
+	if field.allows(operator) {
+		return nil
+	}
+	return p.errorf(ErrOperatorNotAllowed, "'%s' can't be applied to this field", operator)
+}
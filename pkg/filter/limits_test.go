@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Account struct {
+	Name     string   `json:"name"`
+	Email    string   `json:"email" filter:"eq,in"`
+	Password string   `json:"password" filter:"-"`
+	Roles    []string `json:"roles" filter:"any,eq"`
+}
+
+var testAccounts = []Account{
+	{Name: "Marty McFly", Email: "marty@example.com", Password: "hoverboard", Roles: []string{"user"}},
+	{Name: "Doc Brown", Email: "doc@example.com", Password: "flux", Roles: []string{"user", "admin"}},
+}
+
+func TestNewStructFilterE_FilterTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		filterJson string
+		expected   error
+		path       string
+	}{
+		{"hidden field", `{"password": {"eq": "flux"}}`, ErrUnknownField, "$.password"},
+		{"operator not allowed", `{"email": {"contains": "doc"}}`, ErrOperatorNotAllowed, "$.email.contains"},
+		{"quantifier not allowed", `{"roles": {"size": {"gt": 1}}}`, ErrOperatorNotAllowed, "$.roles.size"},
+		{"element operator not allowed", `{"roles": {"any": {"ne": "admin"}}}`, ErrOperatorNotAllowed, "$.roles.any.ne"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStructFilterE[Account](tt.filterJson)
+
+			assert.ErrorIs(t, err, tt.expected)
+			var pathErr *PathError
+			if assert.ErrorAs(t, err, &pathErr) {
+				assert.Equal(t, tt.path, pathErr.Path)
+			}
+		})
+	}
+}
+
+func TestFilterAllForE_FilterTags(t *testing.T) {
+	structFilter, err := NewStructFilterE[Account](`{"email": {"in": ["doc@example.com"]}, "roles": {"any": {"eq": "admin"}}, "name": {"contains": "Doc"}}`)
+	require.NoError(t, err)
+
+	result, err := FilterAllForE(structFilter, testAccounts)
+
+	require.NoError(t, err)
+	assert.Equal(t, testAccounts[1:], result)
+	assert.NotContains(t, GenerateGraphQL[Account](), "password")
+}
+
+func TestStructFilter_UnmarshalJSON_FilterTags(t *testing.T) {
+	var request struct {
+		Filter StructFilter[Account] `json:"filter"`
+	}
+	request.Filter = newStructFilter[Account]()
+
+	err := json.Unmarshal([]byte(`{"filter": [{"password": {"startsWith": "f"}}]}`), &request)
+
+	assert.ErrorIs(t, err, ErrUnknownField)
+}
+
+func TestNewStructFilterE_Limits(t *testing.T) {
+	defaultLimits := DefaultLimits
+	t.Cleanup(func() { DefaultLimits = defaultLimits })
+	DefaultLimits = Limits{MaxDepth: 8, MaxClauses: 4, MaxPatternLength: 16}
+
+	tests := []struct {
+		name       string
+		filterJson string
+		expected   error
+	}{
+		{"depth", strings.Repeat(`{"not": `, 8) + `{"title": {"eq": "x"}}` + strings.Repeat("}", 8), ErrFilterTooComplex},
+		{"clauses", `[{"title": {"ne": "a"}}, {"title": {"ne": "b"}}, {"title": {"ne": "c"}}, {"title": {"ne": "d"}}, {"title": {"ne": "e"}}]`, ErrFilterTooComplex},
+		{"quantifiers", `{"cast": {"any": {"name": {"eq": "a"}}, "all": {"name": {"ne": "b"}}, "none": {"name": {"eq": "c"}}}}`, ErrFilterTooComplex},
+		{"pattern length", `{"title": {"matches": "Back to the Future.*"}}`, ErrFilterTooComplex},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewStructFilterE[Movie](tt.filterJson)
+
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	_, err := NewStructFilterE[Movie](`[{"title": {"ne": "a"}}, {"title": {"ne": "b"}}, {"or": [{"title": {"ne": "c"}}, {"title": {"ne": "d"}}]}]`)
+	assert.NoError(t, err)
+}
+
+func TestNewStructFilterE_NestedRepetition(t *testing.T) {
+	tests := []struct {
+		pattern string
+		valid   bool
+	}{
+		{`^(a+)+$`, false},
+		{`(x*)*y`, false},
+		{`(ab{2,})+`, false},
+		{`((a|b)*c)*`, false},
+		{`^Back to the .*$`, true},
+		{`(ab)+c*`, true},
+		{`(a{2})+`, true},
+		{`(a{1,3})*`, false},
+		{`(a?b)*`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			_, err := NewStructFilterE[Movie](fmt.Sprintf(`{"title": {"imatches": %q}}`, tt.pattern))
+
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidPattern)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package filter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/PaesslerAG/gval"
+)
+
+var typeOfJSONNumber = reflect.TypeFor[json.Number]()
+
+// MapFilter is a filter that is not tied to a Go type, so it can
+// filter maps (e.g. decoded JSON) and raw JSON documents. It uses the
+// same JSON representation, and the same operators, as a StructFilter;
+// since there is no schema, any field name is accepted, and a field
+// that is missing from a value is null. The (dotted) path of a field
+// can select an element of a list by its index, for example:
+//
+//	`{"cast.0.name": {"eq": "Michael J. Fox"}}`
+type MapFilter struct {
+	filterJson json.RawMessage
+	evaluable  gval.Evaluable
+	schema     *schemaField
+}
+
+// NewMapFilter creates a MapFilter from the JSON representation
+// of a filter; it can either be a list of filter objects, or a single
+// filter object. The expression is compiled once, when the filter
+// is created. It panics if the inputJson is not a valid filter.
+func NewMapFilter(inputJson string) MapFilter {
+	mapFilter, err := NewMapFilterE(inputJson)
+	if err != nil {
+		panic(err)
+	}
+
+	return mapFilter
+}
+
+// NewMapFilterE is the same as NewMapFilter, but returns an
+// error instead of panicking if the inputJson is not a valid
+// filter; as with NewStructFilterE, the error is a *PathError
+// (or an errorx.Errors of them).
+func NewMapFilterE(inputJson string) (MapFilter, error) {
+	var mapFilter MapFilter
+	err := mapFilter.UnmarshalJSON([]byte(inputJson))
+	if err != nil {
+		return MapFilter{}, err
+	}
+
+	return mapFilter, nil
+}
+
+// NewMapFilterForE is the same as NewMapFilterE, but the filter is
+// checked against the schema of the given type, which is the type of
+// the values that it filters, in the same way as a StructFilter: its
+// fields must be known, their filter tags limit the operators that
+// they allow, and the dates that they are compared with are parsed
+// with their format tags. It is meant for types that are only known
+// at runtime; if the type is not a struct (or a pointer to a struct),
+// any field name is accepted, as with NewMapFilterE.
+func NewMapFilterForE(t reflect.Type, inputJson string) (MapFilter, error) {
+	mapFilter := MapFilter{schema: structSchemaOf(t)}
+	err := mapFilter.UnmarshalJSON([]byte(inputJson))
+	if err != nil {
+		return MapFilter{}, err
+	}
+
+	return mapFilter, nil
+}
+
+// MarshalJSON returns the JSON representation
+// that the MapFilter was created from.
+func (mf MapFilter) MarshalJSON() ([]byte, error) {
+	if mf.filterJson == nil {
+		return []byte("[]"), nil
+	}
+	return mf.filterJson, nil
+}
+
+// UnmarshalJSON parses and compiles the JSON representation of a
+// filter, so that a MapFilter can be part of a request body.
+func (mf *MapFilter) UnmarshalJSON(data []byte) error {
+	expr, err := parseExpr(data, parseOptions{schema: mf.schema})
+	if err != nil {
+		return err
+	}
+	evaluable, err := Compile(expr)
+	if err != nil {
+		return err
+	}
+	mf.filterJson = bytes.Clone(data)
+	mf.evaluable = evaluable
+
+	return nil
+}
+
+// ShouldInclude determines whether the given value passes the
+// MapFilter. The value can be a map with string keys, a raw JSON
+// document (a []byte or a json.RawMessage), or a struct, which is
+// filtered by the JSON names of its fields. It panics if the value
+// can't be evaluated.
+func (mf MapFilter) ShouldInclude(val any) bool {
+	result, err := mf.ShouldIncludeE(val)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// ShouldIncludeE is the same as ShouldInclude, but returns an error
+// instead of panicking if the value is not a valid JSON document, or
+// can't be evaluated. Evaluation errors are wrapped with ErrTypeMismatch.
+func (mf MapFilter) ShouldIncludeE(val any) (bool, error) {
+	values, err := documentOf(val)
+	if err != nil {
+		return false, err
+	}
+	if mf.evaluable == nil {
+		// The zero MapFilter includes every value.
+		return true, nil
+	}
+	eval, err := mf.evaluable.EvalBool(context.Background(), values)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+	}
+
+	return eval, nil
+}
+
+// documentOf converts the value that a MapFilter is evaluated
+// against into the form that expressions are evaluated against
+// (see valueOf); raw JSON documents are decoded first.
+func documentOf(val any) (any, error) {
+	switch doc := val.(type) {
+	case json.RawMessage:
+		val = []byte(doc)
+	case []byte:
+	default:
+		return documentValueOf(reflect.ValueOf(val)), nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(val.([]byte)))
+	decoder.UseNumber()
+	var decoded any
+	err := decoder.Decode(&decoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON document: %w", ErrTypeMismatch, err)
+	}
+
+	return documentValueOf(reflect.ValueOf(decoded)), nil
+}
+
+// structSchemaOf returns the schema of the given type, if it is
+// a struct or a pointer to a struct; otherwise, it returns nil.
+func structSchemaOf(t reflect.Type) *schemaField {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return schemaOf(t)
+}
+
+// documentValueOf is the same as valueOf, except that maps with
+// string keys become maps of converted values, and json.Numbers
+// become an int64 or a float64, like the numeric fields of a struct.
+func documentValueOf(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case !v.IsValid():
+		return nil
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		if v.IsNil() {
+			return nil
+		}
+		values := make(map[string]any, v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			values[iter.Key().String()] = documentValueOf(iter.Value())
+		}
+		return values
+	case v.Kind() == reflect.Slice || v.Kind() == reflect.Array:
+		if listElem(v.Type()) == nil {
+			break
+		}
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		values := make([]any, v.Len())
+		for i := range values {
+			values[i] = documentValueOf(v.Index(i))
+		}
+		return values
+	case v.Type() == typeOfJSONNumber:
+		n := json.Number(v.String())
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	}
+
+	return valueOf(v)
+}
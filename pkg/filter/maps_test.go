@@ -0,0 +1,280 @@
+package filter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/jsontime"
+	"github.com/tartale/go/pkg/jsonx"
+)
+
+const testMovieDocument = `{
+	"kind": "MOVIE",
+	"title": "Back to the Future",
+	"movieYear": 1985,
+	"rating": 8.5,
+	"releaseDate": "1985-07-03",
+	"director": {"name": "Robert Zemeckis"},
+	"cast": [{"name": "Michael J. Fox"}, {"name": "Christopher Lloyd"}],
+	"genres": ["Adventure", "Comedy", "Sci-Fi"]
+}`
+
+func TestMapFilter_ShouldInclude(t *testing.T) {
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(testMovieDocument), &doc))
+
+	tests := []struct {
+		name       string
+		filterJson string
+		expected   bool
+	}{
+		{"string", `{"title": {"startsWith": "Back"}}`, true},
+		{"number", `{"movieYear": {"eq": 1985}}`, true},
+		{"float", `{"rating": {"gt": 8}}`, true},
+		{"date", `{"releaseDate": {"before": "1990-01-01"}}`, true},
+		{"nested object", `{"director": {"name": {"eq": "Robert Zemeckis"}}}`, true},
+		{"dotted path", `{"director.name": {"ne": "Robert Zemeckis"}}`, false},
+		{"list index", `{"cast.1.name": {"eq": "Christopher Lloyd"}}`, true},
+		{"nested list index", `{"cast": {"0": {"name": {"eq": "Christopher Lloyd"}}}}`, false},
+		{"list index out of range", `{"cast.2.name": {"isNull": true}}`, true},
+		{"quantifier", `{"cast": {"any": {"name": {"contains": "Fox"}}}}`, true},
+		{"list of primitives", `{"genres": {"all": {"ne": "Drama"}}}`, true},
+		{"missing field", `{"budget": {"exists": true}}`, false},
+		{"or", `{"or": [{"kind": {"eq": "SERIES"}}, {"movieYear": {"in": [1985, 1989]}}]}`, true},
+		{"or with a false sibling", `{"kind": {"eq": "SERIES"}, "or": [{"movieYear": {"in": [1985, 1989]}}]}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapFilter := NewMapFilter(tt.filterJson)
+
+			assert.Equal(t, tt.expected, mapFilter.ShouldInclude(doc))
+			assert.Equal(t, tt.expected, mapFilter.ShouldInclude([]byte(testMovieDocument)))
+			assert.Equal(t, tt.expected, mapFilter.ShouldInclude(json.RawMessage(testMovieDocument)))
+		})
+	}
+}
+
+func TestMapFilter_TypedMaps(t *testing.T) {
+	mapFilter := NewMapFilter(`{"labels.color": {"eq": "blue"}, "counts.views": {"gte": 10}, "size": {"eq": 3}}`)
+
+	result := mapFilter.ShouldInclude(map[string]any{
+		"labels": map[string]string{"color": "blue"},
+		"counts": map[string]int{"views": 12},
+		"size":   json.Number("3"),
+	})
+
+	assert.True(t, result)
+}
+
+func TestMapFilter_Structs(t *testing.T) {
+	mapFilter := NewMapFilter(`{"movie.director.name": {"eq": "Robert Zemeckis"}}`)
+
+	assert.True(t, mapFilter.ShouldInclude(map[string]any{"movie": testMovieList[0]}))
+	assert.True(t, mapFilter.ShouldInclude(map[string]any{"movie": &testMovieList[0]}))
+}
+
+func TestMapFilter_KeyOrder(t *testing.T) {
+	filterJsons := []string{
+		`{"or": [{"title": {"eq": "The Shawshank Redemption"}}], "movieYear": {"eq": 1985}}`,
+		`{"movieYear": {"eq": 1985}, "or": [{"title": {"eq": "The Shawshank Redemption"}}]}`,
+		`{"or": [{"title": {"eq": "Back to the Future"}}, {"kind": {"eq": "SERIES"}}], "movieYear": {"eq": 1985}}`,
+		`{"movieYear": {"eq": 1985}, "or": [{"title": {"eq": "Back to the Future"}}, {"kind": {"eq": "SERIES"}}]}`,
+	}
+	expected := []bool{false, false, true, true}
+
+	for i, filterJson := range filterJsons {
+		for _, movie := range testMovieList {
+			doc := json.RawMessage(jsonx.MustMarshal(movie))
+			expectedResult := expected[i] && movie.Title == "Back to the Future"
+
+			assert.Equal(t, expectedResult, NewStructFilter[Movie](filterJson).ShouldInclude(movie), filterJson)
+			assert.Equal(t, expectedResult, NewMapFilter(filterJson).ShouldInclude(doc), filterJson)
+		}
+	}
+}
+
+func TestMapFilter_FieldNames(t *testing.T) {
+	doc := json.RawMessage(`{"first-name": "Michael", "@id": 7, "true": 1, "kind == \"MOVIE\" || true": "x"}`)
+
+	tests := []struct {
+		name       string
+		filterJson string
+		expected   bool
+	}{
+		{"dash", `{"first-name": {"eq": "Michael"}}`, true},
+		{"at sign", `{"@id": {"eq": 7}}`, true},
+		{"keyword", `{"true": {"eq": 2}}`, false},
+		{"keyword in a quantifier", `{"list": {"any": {"true": {"eq": 2}}}}`, false},
+		{"expression", `{"kind == \"MOVIE\" || true": {"eq": "y"}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapFilter := NewMapFilter(tt.filterJson)
+
+			assert.Equal(t, tt.expected, mapFilter.ShouldInclude(doc))
+		})
+	}
+}
+
+func TestMapFilter_FilterAll(t *testing.T) {
+	mapFilter := NewMapFilter(`{"movieYear": {"gte": 1985}}`)
+	docs := []any{
+		json.RawMessage(`{"title": "Back to the Future", "movieYear": 1985}`),
+		json.RawMessage(`{"title": "Who Framed Roger Rabbit", "movieYear": 1988}`),
+		json.RawMessage(`{"title": "Romancing the Stone", "movieYear": 1984}`),
+	}
+
+	result, err := FilterAllE(mapFilter, docs)
+
+	require.NoError(t, err)
+	assert.Equal(t, docs[:2], result)
+}
+
+func TestNewMapFilterE_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		filterJson string
+		expected   error
+		path       string
+	}{
+		{"invalid operator", `{"title": {"like": "Back"}}`, ErrInvalidOperator, "$.title.like"},
+		{"leading list index", `{"0.title": {"eq": "Back"}}`, ErrUnknownField, "$.0.title"},
+		{"empty field name", `{"": {"eq": "Michael"}}`, ErrUnknownField, "$."},
+		{"malformed", `{"title": {"eq": "Back"}`, ErrInvalidFilter, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMapFilterE(tt.filterJson)
+
+			assert.ErrorIs(t, err, tt.expected)
+			var pathErr *PathError
+			if tt.path != "" && assert.ErrorAs(t, err, &pathErr) {
+				assert.Equal(t, tt.path, pathErr.Path)
+			}
+		})
+	}
+}
+
+func TestMapFilter_ShouldIncludeE_InvalidDocument(t *testing.T) {
+	mapFilter := NewMapFilter(`{"title": {"eq": "Back"}}`)
+
+	_, err := mapFilter.ShouldIncludeE([]byte(`{"title": `))
+
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+}
+
+func TestMapFilter_JSON(t *testing.T) {
+	var request struct {
+		Filter MapFilter `json:"filter"`
+	}
+	err := json.Unmarshal([]byte(`{"filter": {"movieYear": {"eq": 1985}}}`), &request)
+	require.NoError(t, err)
+
+	assert.True(t, request.Filter.ShouldInclude(json.RawMessage(testMovieDocument)))
+	data, err := json.Marshal(request)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"filter": {"movieYear": {"eq": 1985}}}`, string(data))
+	assert.True(t, MapFilter{}.ShouldInclude(map[string]any{}))
+}
+
+func TestNewMapFilterForE(t *testing.T) {
+	type event struct {
+		Name string        `json:"name"`
+		When jsontime.Time `json:"when" format:"01/02/2006"`
+	}
+	events := []any{
+		&event{Name: "Launch", When: jsontime.Time{Time: time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)}},
+		&event{Name: "Review", When: jsontime.Time{Time: time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC)}},
+	}
+
+	mapFilter, err := NewMapFilterForE(reflect.TypeFor[*event](), `{"when": {"gt": "01/15/2020"}}`)
+	require.NoError(t, err)
+	assert.Equal(t, events[1:], FilterAll(mapFilter, events))
+
+	tests := []struct {
+		name       string
+		typ        reflect.Type
+		filterJson string
+		expected   error
+		path       string
+	}{
+		{"unknown field", reflect.TypeFor[event](), `{"title": {"eq": "Launch"}}`, ErrUnknownField, "$.title"},
+		{"wrong type", reflect.TypeFor[event](), `{"name": {"eq": 1}}`, ErrTypeMismatch, "$.name.eq"},
+		{"operator not allowed", reflect.TypeFor[Account](), `{"email": {"contains": "example"}}`, ErrInvalidOperator, "$.email.contains"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMapFilterForE(tt.typ, tt.filterJson)
+
+			assert.ErrorIs(t, err, tt.expected)
+			var pathErr *PathError
+			if assert.ErrorAs(t, err, &pathErr) {
+				assert.Equal(t, tt.path, pathErr.Path)
+			}
+		})
+	}
+
+	mapFilter, err = NewMapFilterForE(reflect.TypeFor[map[string]any](), `{"title": {"eq": "Launch"}}`)
+	require.NoError(t, err)
+	assert.True(t, mapFilter.ShouldInclude(map[string]any{"title": "Launch"}))
+}
+
+func TestValidateOrderBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		orderBy  []OrderBy
+		expected error
+		path     string
+	}{
+		{"unknown field", []OrderBy{{Field: "title"}, {Field: "rating"}}, ErrUnknownField, "$[1].field"},
+		{"missing field", []OrderBy{{Direction: Desc}}, ErrUnknownField, "$[0].field"},
+		{"struct", []OrderBy{{Field: "director"}}, ErrInvalidFilter, "$[0].field"},
+		{"unknown direction", []OrderBy{{Field: "director.name", Direction: "up"}}, ErrInvalidOperator, "$[0].direction"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrderBy(reflect.TypeFor[*Movie](), tt.orderBy...)
+
+			assert.ErrorIs(t, err, tt.expected)
+			var pathErr *PathError
+			if assert.ErrorAs(t, err, &pathErr) {
+				assert.Equal(t, tt.path, pathErr.Path)
+			}
+		})
+	}
+
+	assert.NoError(t, ValidateOrderBy(reflect.TypeFor[*Movie](), OrderBy{Field: "movieYear", Direction: Desc}))
+	assert.NoError(t, ValidateOrderBy(reflect.TypeFor[map[string]any](), OrderBy{Field: "rating"}))
+}
+
+func TestMapFilter_LargeIntegers(t *testing.T) {
+	document := `{"id": 9007199254740993}`
+	var doc map[string]any
+	decoder := json.NewDecoder(strings.NewReader(document))
+	decoder.UseNumber()
+	require.NoError(t, decoder.Decode(&doc))
+
+	tests := []struct {
+		name       string
+		filterJson string
+		expected   bool
+	}{
+		{"eq", `{"id": {"eq": 9007199254740993}}`, true},
+		{"eq of the nearest float", `{"id": {"eq": 9007199254740992}}`, false},
+		{"in", `{"id": {"in": [9007199254740992, 9007199254740994]}}`, false},
+		{"gt", `{"id": {"gt": 9007199254740992}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapFilter := NewMapFilter(tt.filterJson)
+
+			assert.Equal(t, tt.expected, mapFilter.ShouldInclude(doc))
+			assert.Equal(t, tt.expected, mapFilter.ShouldInclude([]byte(document)))
+		})
+	}
+}
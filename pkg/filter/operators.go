@@ -0,0 +1,211 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// valueKind describes the kind of literal that
+// an operator accepts in the JSON representation
+// of a filter.
+type valueKind int
+
+const (
+	primitiveValue valueKind = iota
+	orderedValue
+	stringValue
+	boolValue
+	listValue
+	rangeValue
+	timeValue
+	durationValue
+	customValue
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case orderedValue:
+		return "a string or number"
+	case stringValue:
+		return "a string"
+	case boolValue:
+		return "a boolean"
+	case listValue:
+		return "a list of values"
+	case rangeValue:
+		return "a list of two strings or numbers"
+	case timeValue:
+		return "a date"
+	case durationValue:
+		return "a duration"
+	case customValue:
+		return "a JSON value"
+	}
+	return "a string, number or boolean"
+}
+
+// accepts reports whether a single (non-list)
+// literal is acceptable for the value kind.
+func (k valueKind) accepts(v any) bool {
+	if k == customValue {
+		return true
+	}
+	switch v.(type) {
+	case string:
+		return k != boolValue
+	case json.Number:
+		return k == primitiveValue || k == orderedValue || k == listValue || k == rangeValue
+	case bool:
+		return k == primitiveValue || k == boolValue || k == listValue
+	}
+	return false
+}
+
+// operator defines how one of the operators of
+// filter.Operator is converted into a gval expression.
+// If foldCase is set, the case of both the field and the
+// value is folded before they are compared.
+type operator struct {
+	value      valueKind
+	expression func(field string, value any) string
+	foldCase   bool
+}
+
+// operators maps the names of the operators in the JSON
+// representation of a filter (see filter.Operator) to
+// their definitions.
+var operators = map[string]operator{
+	"eq":         {primitiveValue, infix("=="), false},
+	"ne":         {primitiveValue, infix("!="), false},
+	"lte":        {orderedValue, infix("<="), false},
+	"gte":        {orderedValue, infix(">="), false},
+	"lt":         {orderedValue, infix("<"), false},
+	"gt":         {orderedValue, infix(">"), false},
+	"matches":    {stringValue, infix("=~"), false},
+	"in":         {listValue, call("isIn"), false},
+	"nin":        {listValue, negate(call("isIn")), false},
+	"contains":   {stringValue, call("contains"), false},
+	"startsWith": {stringValue, call("startsWith"), false},
+	"endsWith":   {stringValue, call("endsWith"), false},
+	"exists":     {boolValue, isNull(false), false},
+	"isNull":     {boolValue, isNull(true), false},
+	"between":    {rangeValue, between, false},
+	"before":     {timeValue, infix("<"), false},
+	"after":      {timeValue, infix(">"), false},
+	"within":     {durationValue, call("within"), false},
+
+	"ieq":         {stringValue, infix("=="), true},
+	"ine":         {stringValue, infix("!="), true},
+	"icontains":   {stringValue, call("contains"), true},
+	"istartsWith": {stringValue, call("startsWith"), true},
+	"iendsWith":   {stringValue, call("endsWith"), true},
+	"imatches":    {stringValue, imatches, false},
+}
+
+// quantifiers maps the names of the operators that apply an
+// operator object to the elements of a list field (or to its
+// size) to the functions of the filter language that they are
+// rendered as; see Quantifier.
+var quantifiers = map[string]string{
+	"any":  "anyOf",
+	"all":  "allOf",
+	"none": "noneOf",
+	"size": "sizeOf",
+}
+
+// normalizationForms contains the values that are accepted by
+// the "normalize" option of filter.Operator. Besides the standard
+// Unicode normalization forms, "unaccent" removes diacritics
+// (e.g. "Amélie" becomes "Amelie").
+var normalizationForms = map[string]bool{
+	"NFC":      true,
+	"NFD":      true,
+	"NFKC":     true,
+	"NFKD":     true,
+	"unaccent": true,
+}
+
+// infix renders an operator as a gval infix operator,
+// e.g. `$["movieYear"] == 1985`.
+func infix(symbol string) func(string, any) string {
+	return func(field string, value any) string {
+		return fmt.Sprintf("%s %s %s", field, symbol, literal(value))
+	}
+}
+
+// call renders an operator as a call to one of the
+// functions of the filter language, e.g.
+// `contains(title, "Future")`.
+func call(function string) func(string, any) string {
+	return func(field string, value any) string {
+		return fmt.Sprintf("%s(%s, %s)", function, field, literal(value))
+	}
+}
+
+func negate(expression func(string, any) string) func(string, any) string {
+	return func(field string, value any) string {
+		return "!" + expression(field, value)
+	}
+}
+
+// isNull renders the "exists" and "isNull" operators;
+// a false value for either operator inverts the check.
+func isNull(expected bool) func(string, any) string {
+	return func(field string, value any) string {
+		if value == expected {
+			return fmt.Sprintf("isNull(%s)", field)
+		}
+		return fmt.Sprintf("!isNull(%s)", field)
+	}
+}
+
+// imatches renders the "imatches" operator as a
+// case-insensitive regular expression match.
+func imatches(field string, value any) string {
+	return infix("=~")(field, "(?i)"+value.(string))
+}
+
+// between renders the "between" operator as an
+// inclusive range check.
+func between(field string, value any) string {
+	bounds := value.([]any)
+	return fmt.Sprintf("(%s >= %s && %s <= %s)", field, literal(bounds[0]), field, literal(bounds[1]))
+}
+
+// literal renders a decoded JSON value as a gval literal.
+func literal(v any) string {
+	switch val := v.(type) {
+	case string:
+		return strconv.Quote(val)
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	case []any:
+		parts := make([]string, len(val))
+		for i, elem := range val {
+			parts[i] = literal(elem)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case map[string]any:
+		parts := make([]string, 0, len(val))
+		for _, key := range slices.Sorted(maps.Keys(val)) {
+			parts = append(parts, strconv.Quote(key)+": "+literal(val[key]))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	case nil:
+		return "nil"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v)
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
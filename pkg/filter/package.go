@@ -33,9 +33,29 @@ package filter
 // 		filteredMovies := FilterAllFor[Movie](structFilter, movies)
 //
 // The JSON expression operators (e.g. "eq") are defined in the filter.Operator
-// struct. You can also create compound filters (using "and" and "or" operators).
+// struct. You can also create compound filters (using "and", "or" and "not" operators),
+// nested to any depth; see ParseExpr for the rules on how compound filters are combined.
 // The tests include several examples.
 //
+// Fields of nested structs (or pointers to structs) can be filtered with
+// either a nested object, or a dotted path; these are equivalent:
+//
+// 		`[{"director": {"name": {"eq": "Robert Zemeckis"}}}]`
+// 		`[{"director.name": {"eq": "Robert Zemeckis"}}]`
+//
+// If the pointer to a nested struct is nil, the fields of the nested struct are
+// null, so they only match operators like "isNull". List fields (of primitives
+// or structs) can be filtered with the "any", "all", "none" and "size" quantifiers:
+//
+// 		`[{"cast": {"any": {"name": {"eq": "Christopher Lloyd"}}, "size": {"gt": 2}}}]`
+//
+// In URLs and on the command line, filters can also be written in a more compact
+// textual syntax; ParseText converts it into the JSON representation, and FormatText
+// converts the JSON representation back into text:
+//
+// 		filterJson := filter.MustParseText(`title ~ "Back to.*" and movieYear >= 1985 and not kind = "SERIES"`)
+// 		structFilter := NewStructFilter[Movie](filterJson)
+//
 // Additionally, you can "bring your own filter" by defining a struct that uses the
 // filter.Operator type. This is useful if you want the API to have a well-known
 // structure that can be used for swagger documentation, code generation, graphQL
@@ -46,6 +66,7 @@ package filter
 // 			MovieYear *Operator      `json:"movieYear,omitempty"`
 // 			And       []*MovieFilter `json:"and,omitempty"`
 // 			Or        []*MovieFilter `json:"or,omitempty"`
+// 			Not       *MovieFilter   `json:"not,omitempty"`
 // 		}
 //
 // Then, the resolver for this API call would look something like this:
@@ -58,3 +79,80 @@ package filter
 // 		filteredMovies := FilterAllFor(structFilter, movies)
 // 	}
 //
+// Rather than writing these structs by hand, GenerateGo generates them (and the
+// structs for nested fields) from the filtered type, and GenerateGraphQL generates
+// the matching GraphQL input types, so that both stay in sync with the json tags:
+//
+// 		sdl := filter.GenerateGraphQLOperator() + filter.GenerateGraphQL[Movie]()
+// 		src, err := filter.GenerateGo[Movie]("models")
+//
+// For REST APIs, JSONSchema returns a JSON Schema document that validates the JSON
+// representation of a StructFilter for a type, with the operators of each field
+// typed according to the kind of the field; OpenAPISchemas returns the same
+// definitions for the components of an OpenAPI document.
+//
+// To also order and paginate the results, use a Query, which combines a
+// StructFilter with "orderBy", "offset", "limit" and cursor-based ("after")
+// pagination:
+//
+// 		query := NewQuery[Movie](`{"filter": [...], "orderBy": [{"field": "movieYear", "direction": "desc"}], "limit": 10}`)
+// 		page := query.ApplyAll(movies)
+//
+// To filter in the database instead, ToSQL translates a filter into a
+// parameterized WHERE clause; the columns come from the db tags of the fields:
+//
+// 		where, args, err := structFilter.ToSQL(filter.Postgres)
+// 		rows, err := db.Query("SELECT * FROM movies WHERE "+where, args...)
+//
+// To find out why an object did or didn't pass a filter, Explain returns a trace of
+// the evaluation of each clause of the filter against the object, including the values
+// of the fields and the literals that they were compared with; the trace can be
+// marshalled to JSON, e.g. for a debugging endpoint:
+//
+// 		explanation := structFilter.Explain(movie)
+//
+// Predicates that the operators of filter.Operator can't express can be registered as
+// custom operators, with a Go function (or an expression of the filter language); they
+// are then accepted in filters like any other operator:
+//
+// 		filter.RegisterOperator("semver", filter.CustomOperator{Func: matchesSemverRange})
+// 		structFilter := filter.NewStructFilter[Release](`{"version": {"semver": ">=1.2.0"}}`)
+//
+// Data that doesn't have a Go type, such as decoded JSON or raw JSON documents, can be
+// filtered with a MapFilter, which accepts any field name; the dotted path of a field can
+// also select an element of a list by its index:
+//
+// 		mapFilter := filter.NewMapFilter(`{"cast.0.name": {"eq": "Michael J. Fox"}}`)
+// 		docs, err := filter.FilterAllE(mapFilter, []any{json.RawMessage(doc1), json.RawMessage(doc2)})
+//
+// For large inputs and expensive filters, FilterParallelFor evaluates the filter in
+// a bounded pool of goroutines, and yields the objects that pass it in their original
+// order; it stops when the context is done. FilterChan does the same for the objects
+// received from a channel:
+//
+// 		for movie, err := range filter.FilterParallelFor(ctx, structFilter, slices.Values(movies), 8) {
+// 			...
+// 		}
+//
+// The functions above panic if the filter is invalid. When the filter comes from
+// a client, use the error-returning variants instead (NewStructFilterE, FilterAllForE,
+// etc.); their errors wrap errorz.ErrBadRequest, and identify the offending part of
+// the filter with a PathError. The literals of a filter are checked against the kinds
+// of the fields they are compared with, and all of the literals that don't match are
+// reported at once, as an errorx.Errors:
+//
+// 		`{"movieYear": {"eq": "nineteen"}, "title": {"matches": "Back to the ("}}`
+//
+// The fields that clients can filter by, and the operators that they can apply to
+// them, are restricted with the filter tag: a field with a filter tag of "-" can't be
+// filtered at all, and a field with a list of operators only allows those operators:
+//
+// 		type Account struct {
+// 			Email    string `json:"email" filter:"eq,in"`
+// 			Password string `json:"password" filter:"-"`
+// 		}
+//
+// Filters are also limited in their nesting depth, their number of clauses, and the
+// length of their patterns (see DefaultLimits), and patterns with nested repetitions
+// (e.g. "(a+)+") are rejected.
+//
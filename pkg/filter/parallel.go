@@ -0,0 +1,176 @@
+package filter
+
+import (
+	"context"
+	"iter"
+	"runtime"
+)
+
+// FilterParallelFor is the same as FilterFor, but evaluates the filter
+// against up to the given number of values at a time, in separate
+// goroutines; if workers is not positive, it is runtime.GOMAXPROCS(0).
+// The values that pass the filter are yielded in the same order as
+// the input sequence, and no more than the given number of values are
+// read ahead of the value that is being yielded.
+//
+// Since the filter is evaluated in other goroutines, it must be safe
+// for concurrent use (as a StructFilter is), and it returns its errors
+// instead of panicking: the first error, or the error of the context
+// if it is done, is yielded with the zero value, and ends the sequence.
+// The goroutines stop when the sequence ends, or the loop over it is
+// exited early; the sequence then waits for the input sequence to stop,
+// which happens when it yields its next value (or ends), so the input
+// sequence is not read from again after the loop.
+func FilterParallelFor[T any](ctx context.Context, f FiltererOfE[T], vals iter.Seq[T], workers int) iter.Seq2[T, error] {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return func(yield func(T, error) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		stopped := make(chan struct{})
+		defer func() {
+			cancel()
+			<-stopped
+		}()
+
+		// Each job is sent to the workers, and to the ordered
+		// channel, so that its result is waited for in order; the
+		// buffer of the ordered channel bounds the read-ahead.
+		jobs := make(chan parallelJob[T])
+		ordered := make(chan parallelJob[T], workers)
+		go func() {
+			defer close(stopped)
+			defer close(jobs)
+			defer close(ordered)
+			for val := range vals {
+				job := parallelJob[T]{val: val, result: make(chan parallelResult, 1)}
+				select {
+				case ordered <- job:
+				case <-ctx.Done():
+					return
+				}
+				select {
+				case jobs <- job:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		for range workers {
+			go func() {
+				for job := range jobs {
+					if err := ctx.Err(); err != nil {
+						job.result <- parallelResult{err: err}
+						continue
+					}
+					include, err := f.ShouldIncludeE(job.val)
+					job.result <- parallelResult{include: include, err: err}
+				}
+			}()
+		}
+
+		var zero T
+		for job := range ordered {
+			var result parallelResult
+			select {
+			case result = <-job.result:
+			case <-ctx.Done():
+				result.err = ctx.Err()
+			}
+			if result.err != nil {
+				yield(zero, result.err)
+				return
+			}
+			if result.include && !yield(job.val, nil) {
+				return
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// FilterAllParallelForE is a wrapper around FilterParallelFor that
+// accepts and returns slices instead of iterators; it stops and returns
+// the first error encountered while filtering.
+func FilterAllParallelForE[T any](ctx context.Context, f FiltererOfE[T], vals []T, workers int) ([]T, error) {
+	var filterVals []T
+	for val, err := range FilterParallelFor(ctx, f, func(yield func(T) bool) {
+		for _, val := range vals {
+			if !yield(val) {
+				return
+			}
+		}
+	}, workers) {
+		if err != nil {
+			return nil, err
+		}
+		filterVals = append(filterVals, val)
+	}
+
+	return filterVals, nil
+}
+
+// FilterChan is the channel-based version of FilterParallelFor: it
+// filters the values received from the input channel, and sends the
+// ones that pass the filter to the returned channel, in order. When the
+// input channel is closed, or the context is done, or the filter returns
+// an error, the returned channel is closed; the error (if any) can then
+// be received from the error channel, which is closed afterwards.
+func FilterChan[T any](ctx context.Context, f FiltererOfE[T], in <-chan T, workers int) (<-chan T, <-chan error) {
+	out := make(chan T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(out)
+		// The receiving is stopped before the loop is exited,
+		// since FilterParallelFor waits for it to stop.
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		for val, err := range FilterParallelFor(ctx, f, receive(ctx, in), workers) {
+			if err != nil {
+				cancel()
+				errs <- err
+				return
+			}
+			select {
+			case out <- val:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return out, errs
+}
+
+// receive returns a sequence of the values received from the
+// given channel, which ends when the channel is closed, or the
+// context is done.
+func receive[T any](ctx context.Context, in <-chan T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for {
+			select {
+			case val, ok := <-in:
+				if !ok || !yield(val) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+type parallelJob[T any] struct {
+	val    T
+	result chan parallelResult
+}
+
+type parallelResult struct {
+	include bool
+	err     error
+}
@@ -0,0 +1,179 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingFilter includes the even values, and
+// returns an error for the value that it fails on.
+type failingFilter struct {
+	failOn int
+}
+
+var errFailingFilter = errors.New("failing filter")
+
+func (f failingFilter) ShouldIncludeE(val int) (bool, error) {
+	if val == f.failOn {
+		return false, errFailingFilter
+	}
+	return val%2 == 0, nil
+}
+
+func TestFilterAllParallelForE(t *testing.T) {
+	structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+	movies := makeMovies(1000)
+
+	for _, workers := range []int{0, 1, 4, 64} {
+		result, err := FilterAllParallelForE(context.Background(), structFilter, movies, workers)
+
+		require.NoError(t, err)
+		assert.Equal(t, FilterAllFor(structFilter, movies), result)
+	}
+}
+
+func TestFilterParallelFor_Error(t *testing.T) {
+	var result []int
+	var resultErr error
+	for val, err := range FilterParallelFor(context.Background(), failingFilter{failOn: 7}, slices.Values([]int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}), 3) {
+		if err != nil {
+			resultErr = err
+			break
+		}
+		result = append(result, val)
+	}
+
+	assert.ErrorIs(t, resultErr, errFailingFilter)
+	assert.Equal(t, []int{2, 4, 6}, result)
+}
+
+func TestFilterParallelFor_Break(t *testing.T) {
+	vals := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	var result []int
+	for val, err := range FilterParallelFor(context.Background(), failingFilter{failOn: -1}, vals, 2) {
+		require.NoError(t, err)
+		result = append(result, val)
+		if len(result) == 3 {
+			break
+		}
+	}
+
+	assert.Equal(t, []int{0, 2, 4}, result)
+}
+
+func TestFilterParallelFor_BreakWithSlowInput(t *testing.T) {
+	var stopped atomic.Bool
+	vals := func(yield func(int) bool) {
+		defer stopped.Store(true)
+		for i := 0; ; i++ {
+			time.Sleep(10 * time.Millisecond)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	for val, err := range FilterParallelFor(context.Background(), failingFilter{failOn: -1}, vals, 2) {
+		require.NoError(t, err)
+		if val == 2 {
+			break
+		}
+	}
+
+	assert.True(t, stopped.Load())
+}
+
+func TestFilterParallelFor_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vals := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+
+	var result []int
+	var resultErr error
+	for val, err := range FilterParallelFor(ctx, failingFilter{failOn: -1}, vals, 2) {
+		if err != nil {
+			resultErr = err
+			break
+		}
+		result = append(result, val)
+		if len(result) == 3 {
+			cancel()
+		}
+	}
+
+	assert.ErrorIs(t, resultErr, context.Canceled)
+	assert.Equal(t, []int{0, 2, 4}, result[:3])
+}
+
+func TestFilterChan(t *testing.T) {
+	structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+	movies := makeMovies(100)
+	in := make(chan Movie)
+	go func() {
+		defer close(in)
+		for _, movie := range movies {
+			in <- movie
+		}
+	}()
+
+	out, errs := FilterChan(context.Background(), structFilter, in, 4)
+	var result []Movie
+	for movie := range out {
+		result = append(result, movie)
+	}
+
+	assert.NoError(t, <-errs)
+	assert.Equal(t, FilterAllFor(structFilter, movies), result)
+}
+
+func TestFilterChan_Error(t *testing.T) {
+	in := make(chan int, 10)
+	for i := 1; i <= 10; i++ {
+		in <- i
+	}
+	close(in)
+
+	out, errs := FilterChan(context.Background(), failingFilter{failOn: 5}, in, 2)
+	var result []int
+	for val := range out {
+		result = append(result, val)
+	}
+
+	assert.ErrorIs(t, <-errs, errFailingFilter)
+	assert.Equal(t, []int{2, 4}, result)
+}
+
+func TestFilterChan_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan int)
+
+	out, errs := FilterChan(ctx, failingFilter{failOn: -1}, in, 2)
+	in <- 2
+	assert.Equal(t, 2, <-out)
+	cancel()
+	for range out {
+	}
+
+	assert.ErrorIs(t, <-errs, context.Canceled)
+}
@@ -0,0 +1,541 @@
+package filter
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tartale/go/pkg/errorx"
+)
+
+// Direction is the direction in which an OrderBy
+// orders the values of a field.
+type Direction string
+
+const (
+	Asc  Direction = "asc"
+	Desc Direction = "desc"
+)
+
+// OrderBy orders a list of objects by the values of one of their
+// fields. The Field is the JSON name of the field, or a dotted path to
+// a field of a nested struct (e.g. "director.name"); the Direction is
+// either "asc" (the default) or "desc", in any case. Null values are
+// ordered before any other value.
+type OrderBy struct {
+	Field     string    `json:"field"`
+	Direction Direction `json:"direction,omitempty"`
+}
+
+func (o OrderBy) descending() bool {
+	return strings.EqualFold(string(o.Direction), string(Desc))
+}
+
+// Query combines a StructFilter for type T with the ordering and
+// pagination of the objects that pass the filter. Its JSON
+// representation looks something like this:
+//
+//	{
+//		"filter": [{"kind": {"eq": "MOVIE"}}],
+//		"orderBy": [{"field": "movieYear", "direction": "desc"}, {"field": "title"}],
+//		"offset": 20,
+//		"limit": 10
+//	}
+//
+// A Limit of zero means that the number of results is not limited.
+// For cursor-based pagination, use the cursor of the last object of
+// a page (see Cursor) as the After of the query for the next page;
+// for the pages to be stable, the last OrderBy should be a field that
+// is unique to each object.
+type Query[T any] struct {
+	Filter  StructFilter[T] `json:"filter"`
+	OrderBy []OrderBy       `json:"orderBy,omitempty"`
+	Offset  int             `json:"offset,omitempty"`
+	Limit   int             `json:"limit,omitempty"`
+	After   string          `json:"after,omitempty"`
+}
+
+// NewQuery creates a query for type T from its JSON
+// representation. It panics if the inputJson is not
+// a valid query for type T.
+func NewQuery[T any](inputJson string) Query[T] {
+	query, err := NewQueryE[T](inputJson)
+	if err != nil {
+		panic(err)
+	}
+
+	return query
+}
+
+// NewQueryE is the same as NewQuery, but returns an error
+// instead of panicking if the inputJson is not a valid query
+// for type T. As with NewStructFilterE, the error is a *PathError
+// (e.g. with a path of "$.orderBy[1].field").
+func NewQueryE[T any](inputJson string) (Query[T], error) {
+	var input struct {
+		Filter  json.RawMessage `json:"filter"`
+		OrderBy []OrderBy       `json:"orderBy"`
+		Offset  int             `json:"offset"`
+		Limit   int             `json:"limit"`
+		After   string          `json:"after"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(inputJson))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&input)
+	if err != nil {
+		return Query[T]{}, &PathError{Path: "$", Err: fmt.Errorf("%w: %w", ErrInvalidFilter, err)}
+	}
+	query := Query[T]{
+		OrderBy: input.OrderBy,
+		Offset:  input.Offset,
+		Limit:   input.Limit,
+		After:   input.After,
+	}
+	filterJson := "[]"
+	if len(input.Filter) > 0 && !bytes.Equal(input.Filter, []byte("null")) {
+		filterJson = string(input.Filter)
+	}
+	query.Filter, err = NewStructFilterE[T](filterJson)
+	if err != nil {
+		errs, ok := err.(errorx.Errors)
+		if !ok {
+			errs = errorx.Errors{err}
+		}
+		for _, err := range errs {
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				pathErr.Path = "$.filter" + strings.TrimPrefix(pathErr.Path, "$")
+			}
+		}
+		return Query[T]{}, err
+	}
+	err = query.Validate()
+	if err != nil {
+		return Query[T]{}, err
+	}
+
+	return query, nil
+}
+
+// UnmarshalJSON unmarshals the JSON representation
+// of a query; see NewQueryE.
+func (q *Query[T]) UnmarshalJSON(data []byte) error {
+	query, err := NewQueryE[T](string(data))
+	if err != nil {
+		return err
+	}
+	*q = query
+
+	return nil
+}
+
+// ValidateOrderBy checks that the given ordering is valid for the
+// given type, which is the type of the values that it sorts; it is
+// meant for types that are only known at runtime, such as with
+// NewMapFilterForE. The fields must be known, if the type is a struct
+// (or a pointer to a struct), and can't be structs or lists. The error
+// is a *PathError, with a path of the ordering (e.g. "$[1].field").
+func ValidateOrderBy(t reflect.Type, orderBy ...OrderBy) error {
+	schema := structSchemaOf(t)
+	for i, o := range orderBy {
+		path := fmt.Sprintf("$[%d]", i)
+		if o.Field == "" {
+			return &PathError{Path: path + ".field", Err: fmt.Errorf("%w: the field is required", ErrUnknownField)}
+		}
+		if schema != nil {
+			field := schema
+			for _, name := range strings.Split(o.Field, ".") {
+				nested, ok := field.field(name)
+				if !ok {
+					return &PathError{Path: path + ".field", Err: fmt.Errorf("%w: '%s'", ErrUnknownField, o.Field)}
+				}
+				field = nested
+			}
+			if len(field.fields) > 0 || field.elem != nil {
+				return &PathError{Path: path + ".field", Err: fmt.Errorf("%w: can't order by '%s', which is a struct or a list", ErrInvalidFilter, o.Field)}
+			}
+		}
+		if o.Direction != "" && !o.descending() && !strings.EqualFold(string(o.Direction), string(Asc)) {
+			return &PathError{Path: path + ".direction", Err: fmt.Errorf("%w: unknown direction '%s'", ErrInvalidOperator, o.Direction)}
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the ordering and pagination of the
+// query are valid for type T; the error is a *PathError.
+func (q Query[T]) Validate() error {
+	err := ValidateOrderBy(reflect.TypeFor[T](), q.OrderBy...)
+	if err != nil {
+		var pathErr *PathError
+		if errors.As(err, &pathErr) {
+			pathErr.Path = "$.orderBy" + strings.TrimPrefix(pathErr.Path, "$")
+		}
+		return err
+	}
+	if q.Offset < 0 {
+		return &PathError{Path: "$.offset", Err: fmt.Errorf("%w: offset can't be negative", ErrInvalidFilter)}
+	}
+	if q.Limit < 0 {
+		return &PathError{Path: "$.limit", Err: fmt.Errorf("%w: limit can't be negative", ErrInvalidFilter)}
+	}
+	if q.After != "" {
+		if _, err := q.cursor(); err != nil {
+			return &PathError{Path: "$.after", Err: fmt.Errorf("%w: %w", ErrInvalidFilter, err)}
+		}
+	}
+
+	return nil
+}
+
+// Apply takes a sequence iterator and returns an iterator
+// over the objects of the sequence that pass the filter of
+// the query, in the order and with the pagination of the query.
+// It panics if the query is not valid, or if the filter can't
+// be evaluated.
+func (q Query[T]) Apply(vals iter.Seq[T]) iter.Seq[T] {
+	err := q.Validate()
+	if err != nil {
+		panic(err)
+	}
+
+	return q.page(FilterFor(q.Filter, vals))
+}
+
+// ApplyAll is a wrapper around Apply that
+// accepts and returns slices instead of iterators.
+func (q Query[T]) ApplyAll(vals []T) []T {
+	return slices.Collect(q.Apply(slices.Values(vals)))
+}
+
+// ApplyAllE is the same as ApplyAll, but returns an error instead
+// of panicking if the query is not valid, or if the filter can't
+// be evaluated.
+func (q Query[T]) ApplyAllE(vals []T) ([]T, error) {
+	err := q.Validate()
+	if err != nil {
+		return nil, err
+	}
+	filterVals, err := FilterAllForE(q.Filter, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Collect(q.page(slices.Values(filterVals))), nil
+}
+
+// Cursor returns an opaque cursor for the position of the given
+// object in the order of the query; the results of a query with
+// this cursor as its After start after the given object.
+func (q Query[T]) Cursor(val T) string {
+	keys := sortKeys(val, q.OrderBy)
+	keysJson, err := json.Marshal(keys)
+	if err != nil {
+		panic(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(keysJson)
+}
+
+// cursor decodes the After cursor of the query; its numbers are
+// decoded as json.Numbers, which compare converts into exact
+// numbers (see numberOf), so that large integers keep their precision.
+func (q Query[T]) cursor() ([]any, error) {
+	if len(q.OrderBy) == 0 {
+		return nil, fmt.Errorf("a cursor requires the query to have an order")
+	}
+	keysJson, err := base64.RawURLEncoding.DecodeString(q.After)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(keysJson))
+	decoder.UseNumber()
+	var keys []any
+	err = decoder.Decode(&keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if len(keys) != len(q.OrderBy) {
+		return nil, fmt.Errorf("invalid cursor: the cursor is not for the order of the query")
+	}
+
+	return keys, nil
+}
+
+// page orders the given sequence, and applies
+// the pagination of the query to it.
+func (q Query[T]) page(vals iter.Seq[T]) iter.Seq[T] {
+	vals = SortFor(vals, q.OrderBy...)
+	if q.After != "" {
+		cursor, err := q.cursor()
+		if err != nil {
+			panic(err)
+		}
+		vals = skipTo(vals, cursor, q.OrderBy)
+	}
+
+	return Paginate(vals, q.Offset, q.Limit)
+}
+
+// SortFor takes a sequence iterator and returns an iterator over the
+// same objects, ordered by the given fields; objects that are equal
+// in that order keep their original order. The fields are not
+// validated; a field that does not exist has a null value.
+// Because all the objects must be read before the first one can be
+// returned, the sequence must be finite.
+func SortFor[T any](vals iter.Seq[T], orderBy ...OrderBy) iter.Seq[T] {
+	if len(orderBy) == 0 {
+		return vals
+	}
+	return func(yield func(T) bool) {
+		type sortItem struct {
+			val  T
+			keys []any
+		}
+		var items []sortItem
+		for v := range vals {
+			items = append(items, sortItem{val: v, keys: sortKeys(v, orderBy)})
+		}
+		slices.SortStableFunc(items, func(a, b sortItem) int {
+			return compareKeys(a.keys, b.keys, orderBy)
+		})
+		for _, item := range items {
+			if !yield(item.val) {
+				break
+			}
+		}
+	}
+}
+
+// Paginate takes a sequence iterator and returns an iterator that
+// skips the first offset objects, and then returns at most limit
+// objects; a limit of zero means that the number of objects is
+// not limited.
+func Paginate[T any](vals iter.Seq[T], offset, limit int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		i, n := 0, 0
+		for v := range vals {
+			if i++; i <= offset {
+				continue
+			}
+			if limit > 0 && n >= limit {
+				break
+			}
+			n++
+			if !yield(v) {
+				break
+			}
+		}
+	}
+}
+
+// skipTo skips the objects of an ordered sequence up to (and
+// including) the position with the given sort keys.
+func skipTo[T any](vals iter.Seq[T], cursor []any, orderBy []OrderBy) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		skipping := true
+		for v := range vals {
+			if skipping && compareKeys(sortKeys(v, orderBy), cursor, orderBy) <= 0 {
+				continue
+			}
+			skipping = false
+			if !yield(v) {
+				break
+			}
+		}
+	}
+}
+
+// sortKeys returns the values of the fields of the given
+// object that it is ordered by.
+func sortKeys(val any, orderBy []OrderBy) []any {
+	values := valueOf(reflect.ValueOf(val))
+	keys := make([]any, len(orderBy))
+	for i, o := range orderBy {
+		keys[i] = selectPath(values, strings.Split(o.Field, "."))
+	}
+
+	return keys
+}
+
+func compareKeys(a, b []any, orderBy []OrderBy) int {
+	for i, o := range orderBy {
+		c := compare(a[i], b[i])
+		if o.descending() {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// compare orders two values: null is ordered before any other
+// value, dates and numbers are ordered by their values, false
+// is ordered before true, and any other values are ordered
+// as strings. A json.Number is converted into a number
+// (see numberOf).
+func compare(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	a, b = numberOf(a), numberOf(b)
+	if at, bt, ok, err := toTimes(a, b); ok && err == nil {
+		return at.Compare(bt)
+	}
+	if c, ok := compareNumbers(a, b); ok {
+		return c
+	}
+	if ab, ok := a.(bool); ok {
+		if bb, ok := b.(bool); ok {
+			return cmp.Compare(boolToInt(ab), boolToInt(bb))
+		}
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// numberOf converts v, if it is a json.Number, into an int64,
+// or a uint64 if it is too large for an int64, or else into a
+// float64; other values are returned unchanged.
+func numberOf(v any) any {
+	n, ok := v.(json.Number)
+	if !ok {
+		return v
+	}
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return u
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+
+	return v
+}
+
+// compareNumbers orders two numbers by their values; integers
+// are compared exactly, even with floats (e.g. 1<<53 + 1 is
+// greater than float64(1<<53)), and any other numbers are
+// compared as float64s. The result is false if either value
+// is not a number.
+func compareNumbers(a, b any) (int, bool) {
+	av, bv := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isSigned(av) && isSigned(bv):
+		return cmp.Compare(av.Int(), bv.Int()), true
+	case isUnsigned(av) && isUnsigned(bv):
+		return cmp.Compare(av.Uint(), bv.Uint()), true
+	case isSigned(av) && isUnsigned(bv):
+		if av.Int() < 0 {
+			return -1, true
+		}
+		return cmp.Compare(uint64(av.Int()), bv.Uint()), true
+	case isUnsigned(av) && isSigned(bv):
+		if bv.Int() < 0 {
+			return 1, true
+		}
+		return cmp.Compare(av.Uint(), uint64(bv.Int())), true
+	case isSigned(av) && isFloat(bv):
+		return compareIntFloat(av.Int(), bv.Float()), true
+	case isFloat(av) && isSigned(bv):
+		return -compareIntFloat(bv.Int(), av.Float()), true
+	case isUnsigned(av) && isFloat(bv):
+		return compareUintFloat(av.Uint(), bv.Float()), true
+	case isFloat(av) && isUnsigned(bv):
+		return -compareUintFloat(bv.Uint(), av.Float()), true
+	}
+	af, ok := toFloat(a)
+	if !ok {
+		return 0, false
+	}
+	bf, ok := toFloat(b)
+	if !ok {
+		return 0, false
+	}
+
+	return cmp.Compare(af, bf), true
+}
+
+// compareIntFloat orders an integer and a float exactly, by comparing
+// the integer with the integral part of the float, and then with its
+// fractional part. NaN is ordered before any number, as by cmp.Compare.
+func compareIntFloat(i int64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 1
+	case f >= math.MaxInt64:
+		return -1
+	case f < math.MinInt64:
+		return 1
+	}
+	t := math.Trunc(f)
+	if c := cmp.Compare(i, int64(t)); c != 0 {
+		return c
+	}
+	return cmp.Compare(t, f)
+}
+
+// compareUintFloat is the same as compareIntFloat,
+// for an unsigned integer.
+func compareUintFloat(u uint64, f float64) int {
+	switch {
+	case math.IsNaN(f):
+		return 1
+	case f >= math.MaxUint64:
+		return -1
+	case f < 0:
+		return 1
+	}
+	t := math.Trunc(f)
+	if c := cmp.Compare(u, uint64(t)); c != 0 {
+		return c
+	}
+	return cmp.Compare(t, f)
+}
+
+func isFloat(v reflect.Value) bool {
+	return v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+func isSigned(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUnsigned(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,193 @@
+package filter
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorx"
+	"github.com/tartale/go/pkg/errorz"
+)
+
+var queryMovieList = []Movie{
+	{Kind: MOVIE, Title: "Back to the Future", MovieYear: 1985, Director: &Person{Name: "Robert Zemeckis"}},
+	{Kind: MOVIE, Title: "Back to the Future Part II", MovieYear: 1989, Director: &Person{Name: "Robert Zemeckis"}},
+	{Kind: MOVIE, Title: "Back to the Future Part III", MovieYear: 1990, Director: &Person{Name: "Robert Zemeckis"}},
+	{Kind: MOVIE, Title: "The Shawshank Redemption", MovieYear: 1994, Director: &Person{Name: "Frank Darabont"}},
+	{Kind: SERIES, Title: "Back to the Future: The Animated Series", MovieYear: 1991},
+	{Kind: MOVIE, Title: "Who Framed Roger Rabbit", MovieYear: 1988, Director: &Person{Name: "Robert Zemeckis"}},
+}
+
+type queryTestCase struct {
+	name           string
+	queryJson      string
+	expectedTitles []string
+}
+
+func TestQuery_ApplyAll(t *testing.T) {
+	cases := []queryTestCase{
+		{
+			name:      "filter only",
+			queryJson: `{"filter": [{"title": {"startsWith": "Back"}}, {"kind": {"eq": "MOVIE"}}]}`,
+			expectedTitles: []string{
+				"Back to the Future",
+				"Back to the Future Part II",
+				"Back to the Future Part III",
+			},
+		},
+		{
+			name:      "order by descending",
+			queryJson: `{"filter": {"director.name": {"eq": "Robert Zemeckis"}}, "orderBy": [{"field": "movieYear", "direction": "desc"}]}`,
+			expectedTitles: []string{
+				"Back to the Future Part III",
+				"Back to the Future Part II",
+				"Who Framed Roger Rabbit",
+				"Back to the Future",
+			},
+		},
+		{
+			name:      "order by multiple fields",
+			queryJson: `{"orderBy": [{"field": "director.name", "direction": "DESC"}, {"field": "title"}]}`,
+			expectedTitles: []string{
+				"Back to the Future",
+				"Back to the Future Part II",
+				"Back to the Future Part III",
+				"Who Framed Roger Rabbit",
+				"The Shawshank Redemption",
+				"Back to the Future: The Animated Series",
+			},
+		},
+		{
+			name:           "limit",
+			queryJson:      `{"orderBy": [{"field": "movieYear"}], "limit": 2}`,
+			expectedTitles: []string{"Back to the Future", "Who Framed Roger Rabbit"},
+		},
+		{
+			name:           "offset and limit",
+			queryJson:      `{"filter": {"kind": {"eq": "MOVIE"}}, "orderBy": [{"field": "movieYear"}], "offset": 2, "limit": 2}`,
+			expectedTitles: []string{"Back to the Future Part II", "Back to the Future Part III"},
+		},
+		{
+			name:           "offset past the end",
+			queryJson:      `{"offset": 10}`,
+			expectedTitles: nil,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := NewQueryE[Movie](tc.queryJson)
+			require.NoError(t, err)
+
+			result, err := query.ApplyAllE(queryMovieList)
+			require.NoError(t, err)
+
+			var titles []string
+			for _, movie := range result {
+				titles = append(titles, movie.Title)
+			}
+			assert.Equal(t, tc.expectedTitles, titles)
+		})
+	}
+}
+
+func TestQuery_Cursor(t *testing.T) {
+	query := NewQuery[Movie](`{"orderBy": [{"field": "movieYear", "direction": "desc"}, {"field": "title"}], "limit": 4}`)
+
+	var titles []string
+	for page := query.ApplyAll(queryMovieList); len(page) > 0; page = query.ApplyAll(queryMovieList) {
+		for _, movie := range page {
+			titles = append(titles, movie.Title)
+		}
+		query.After = query.Cursor(page[len(page)-1])
+	}
+
+	assert.Equal(t, []string{
+		"The Shawshank Redemption",
+		"Back to the Future: The Animated Series",
+		"Back to the Future Part III",
+		"Back to the Future Part II",
+		"Who Framed Roger Rabbit",
+		"Back to the Future",
+	}, titles)
+}
+
+func TestQuery_CursorWithLargeIntegers(t *testing.T) {
+	type Event struct {
+		ID int64 `json:"id"`
+	}
+	events := []Event{{ID: 1<<53 + 2}, {ID: 1 << 53}, {ID: 1<<53 + 1}}
+	query := NewQuery[Event](`{"orderBy": [{"field": "id"}], "limit": 1}`)
+
+	var ids []int64
+	for page := query.ApplyAll(events); len(page) > 0; page = query.ApplyAll(events) {
+		ids = append(ids, page[0].ID)
+		query.After = query.Cursor(page[len(page)-1])
+	}
+
+	assert.Equal(t, []int64{1 << 53, 1<<53 + 1, 1<<53 + 2}, ids)
+}
+
+func TestQuery_ComposesWithFilterFor(t *testing.T) {
+	structFilter := NewStructFilter[Movie](`{"movieYear": {"lt": 1990}}`)
+	query := NewQuery[Movie](`{"orderBy": [{"field": "title", "direction": "desc"}], "limit": 1}`)
+
+	result := slices.Collect(query.Apply(FilterFor(structFilter, slices.Values(queryMovieList))))
+
+	require.Len(t, result, 1)
+	assert.Equal(t, "Who Framed Roger Rabbit", result[0].Title)
+}
+
+func TestQuery_UnmarshalJSON(t *testing.T) {
+	var request struct {
+		Query Query[Movie] `json:"query"`
+	}
+
+	err := json.Unmarshal([]byte(`{"query": {"filter": {"kind": {"eq": "SERIES"}}, "orderBy": [{"field": "title"}]}}`), &request)
+
+	require.NoError(t, err)
+	result := request.Query.ApplyAll(queryMovieList)
+	require.Len(t, result, 1)
+	assert.Equal(t, "Back to the Future: The Animated Series", result[0].Title)
+}
+
+func TestNewQueryE_Errors(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "unknown query field", filterJSON: `{"sort": "title"}`, err: ErrInvalidFilter, path: "$"},
+		{name: "invalid filter", filterJSON: `{"filter": [{"title": {"like": "Back"}}]}`, err: ErrInvalidOperator, path: "$.filter[0].title.like"},
+		{name: "unknown order field", filterJSON: `{"orderBy": [{"field": "title"}, {"field": "rating"}]}`, err: ErrUnknownField, path: "$.orderBy[1].field"},
+		{name: "order by a struct", filterJSON: `{"orderBy": [{"field": "director"}]}`, err: ErrInvalidFilter, path: "$.orderBy[0].field"},
+		{name: "order by a list", filterJSON: `{"orderBy": [{"field": "genres"}]}`, err: ErrInvalidFilter, path: "$.orderBy[0].field"},
+		{name: "unknown direction", filterJSON: `{"orderBy": [{"field": "title", "direction": "up"}]}`, err: ErrInvalidOperator, path: "$.orderBy[0].direction"},
+		{name: "negative limit", filterJSON: `{"limit": -1}`, err: ErrInvalidFilter, path: "$.limit"},
+		{name: "invalid cursor", filterJSON: `{"orderBy": [{"field": "title"}], "after": "not a cursor"}`, err: ErrInvalidFilter, path: "$.after"},
+		{name: "cursor without an order", filterJSON: `{"after": "WyJ0aXRsZSJd"}`, err: ErrInvalidFilter, path: "$.after"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewQueryE[Movie](tc.filterJSON)
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorIs(t, err, errorz.ErrBadRequest)
+			var pathErr *PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, tc.path, pathErr.Path)
+		})
+	}
+}
+
+func TestNewQueryE_FilterErrors(t *testing.T) {
+	_, err := NewQueryE[Movie](`{"filter": [{"movieYear": {"eq": "nineteen"}}, {"title": {"eq": 1985}}]}`)
+
+	var errs errorx.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2)
+	var pathErr *PathError
+	require.ErrorAs(t, errs[0], &pathErr)
+	assert.Equal(t, "$.filter[0].movieYear.eq", pathErr.Path)
+	require.ErrorAs(t, errs[1], &pathErr)
+	assert.Equal(t, "$.filter[1].title.eq", pathErr.Path)
+}
@@ -0,0 +1,306 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dialect defines the parts of the SQL that ToSQL
+// generates that differ between databases.
+type Dialect struct {
+	// Placeholder renders the bind parameter
+	// with the given (1-based) index.
+	Placeholder func(n int) string
+	// RegexpOperator is the infix operator that matches
+	// a column against a regular expression.
+	RegexpOperator string
+	// QuoteIdentifier quotes the name of a column (or of the table
+	// of a nested struct), so that it can be a keyword, or contain
+	// any character; if it is nil, the names are not quoted.
+	QuoteIdentifier func(name string) string
+}
+
+// SQLite is the dialect of SQLite; note that SQLite only supports
+// the "matches" operators if a regexp() function has been registered.
+var SQLite = Dialect{
+	Placeholder:     func(int) string { return "?" },
+	RegexpOperator:  "REGEXP",
+	QuoteIdentifier: quoteIdentifier(`"`),
+}
+
+// MySQL is the dialect of MySQL (and MariaDB).
+var MySQL = Dialect{
+	Placeholder:     func(int) string { return "?" },
+	RegexpOperator:  "REGEXP",
+	QuoteIdentifier: quoteIdentifier("`"),
+}
+
+// Postgres is the dialect of PostgreSQL.
+var Postgres = Dialect{
+	Placeholder:     func(n int) string { return "$" + strconv.Itoa(n) },
+	RegexpOperator:  "~",
+	QuoteIdentifier: quoteIdentifier(`"`),
+}
+
+// quoteIdentifier returns a function that encloses a name
+// in the given quote, which is doubled within the name.
+func quoteIdentifier(quote string) func(name string) string {
+	return func(name string) string {
+		return quote + strings.ReplaceAll(name, quote, quote+quote) + quote
+	}
+}
+
+// ToSQL translates a filter for type T into a parameterized SQL WHERE
+// clause (without the "WHERE" keyword), and the arguments for its bind
+// parameters. The filter is anything that marshals into the JSON
+// representation of a filter for type T, such as a StructFilter[T],
+// or a hand-written filter struct; for example, this filter:
+//
+//	`{"or": [{"kind": {"eq": "MOVIE"}}, {"title": {"startsWith": "Back"}}]}`
+//
+// is translated into:
+//
+//	`"kind" = $1 OR "title" LIKE $2 ESCAPE '\'`, []any{"MOVIE", "Back%"}
+//
+// The column of a field is the name in its db tag or, if it doesn't
+// have one, its JSON name; the column of a field of a nested struct
+// is qualified by the column of the nested struct (e.g.
+// "director"."name"), so that it can refer to a joined table. The
+// names are quoted by the QuoteIdentifier of the dialect.
+//
+// As in filters that are evaluated in memory, "ne" and "nin" are true
+// for null columns. The case-insensitive operators (e.g. "ieq") compare
+// the LOWER of the column with the lowercase of the value; unlike the
+// full case folding of filters that are evaluated in memory, this
+// doesn't match the different forms of some letters (e.g. "ß" and
+// "ss"), and, depending on the database, may only lower ASCII letters.
+// The quantifiers and the "normalize" option can't be translated, and
+// result in an ErrUnsupportedOperator.
+func ToSQL[T any](filter any, dialect Dialect) (string, []any, error) {
+	filterJson, err := json.Marshal(filter)
+	if err != nil {
+		return "", nil, err
+	}
+	schema := schemaOf(reflect.TypeFor[T]())
+	expr, err := parseExpr(filterJson, parseOptions{schema: schema})
+	if err != nil {
+		return "", nil, err
+	}
+	b := sqlBuilder{schema: schema, dialect: dialect}
+	where, err := b.expression(expr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return where, b.args, nil
+}
+
+// ToSQL translates the StructFilter into a parameterized
+// SQL WHERE clause; see the ToSQL function.
+func (sf StructFilter[T]) ToSQL(dialect Dialect) (string, []any, error) {
+	return ToSQL[T](sf, dialect)
+}
+
+type sqlBuilder struct {
+	schema  *schemaField
+	dialect Dialect
+	args    []any
+}
+
+func (b *sqlBuilder) expression(expr Expr) (string, error) {
+	switch e := expr.(type) {
+	case And:
+		if len(e) == 0 {
+			return "1 = 1", nil
+		}
+		return b.join(e, " AND ")
+	case Or:
+		if len(e) == 0 {
+			return "1 = 0", nil
+		}
+		return b.join(e, " OR ")
+	case Not:
+		sql, err := b.expression(e.Expr)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT (%s)", sql), nil
+	case Comparison:
+		return b.comparison(e)
+	case Quantifier:
+		return "", fmt.Errorf("%w: '%s' can't be translated into SQL", ErrUnsupportedOperator, e.Quantifier)
+	}
+
+	return "", fmt.Errorf("%w: %T can't be translated into SQL", ErrUnsupportedOperator, expr)
+}
+
+func (b *sqlBuilder) join(exprs []Expr, separator string) (string, error) {
+	parts := make([]string, len(exprs))
+	for i, e := range exprs {
+		sql, err := b.expression(e)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = sql
+		if !isAtomic(e) && len(exprs) > 1 {
+			parts[i] = "(" + parts[i] + ")"
+		}
+	}
+
+	return strings.Join(parts, separator), nil
+}
+
+func (b *sqlBuilder) comparison(c Comparison) (string, error) {
+	if c.Normalize != "" {
+		return "", fmt.Errorf("%w: 'normalize' can't be translated into SQL", ErrUnsupportedOperator)
+	}
+	field, column, err := b.column(c.Field)
+	if err != nil {
+		return "", err
+	}
+	value := sqlValue(c.Value, field)
+
+	switch c.Operator {
+	case "eq":
+		return fmt.Sprintf("%s = %s", column, b.arg(value)), nil
+	case "ne":
+		return fmt.Sprintf("(%s <> %s OR %s IS NULL)", column, b.arg(value), column), nil
+	case "lt", "before":
+		return fmt.Sprintf("%s < %s", column, b.arg(value)), nil
+	case "lte":
+		return fmt.Sprintf("%s <= %s", column, b.arg(value)), nil
+	case "gt", "after":
+		return fmt.Sprintf("%s > %s", column, b.arg(value)), nil
+	case "gte":
+		return fmt.Sprintf("%s >= %s", column, b.arg(value)), nil
+	case "matches":
+		return fmt.Sprintf("%s %s %s", column, b.dialect.RegexpOperator, b.arg(value)), nil
+	case "imatches":
+		return fmt.Sprintf("%s %s %s", column, b.dialect.RegexpOperator, b.arg("(?i)"+value.(string))), nil
+	case "in":
+		if len(value.([]any)) == 0 {
+			return "1 = 0", nil
+		}
+		return fmt.Sprintf("%s IN (%s)", column, b.list(value.([]any))), nil
+	case "nin":
+		if len(value.([]any)) == 0 {
+			return "1 = 1", nil
+		}
+		return fmt.Sprintf("(%s NOT IN (%s) OR %s IS NULL)", column, b.list(value.([]any)), column), nil
+	case "contains", "startsWith", "endsWith":
+		return fmt.Sprintf(`%s LIKE %s ESCAPE '\'`, column, b.arg(likePattern(c.Operator, value.(string)))), nil
+	case "ieq":
+		return fmt.Sprintf("LOWER(%s) = %s", column, b.arg(strings.ToLower(value.(string)))), nil
+	case "ine":
+		return fmt.Sprintf("(LOWER(%s) <> %s OR %s IS NULL)", column, b.arg(strings.ToLower(value.(string))), column), nil
+	case "icontains", "istartsWith", "iendsWith":
+		pattern := likePattern(strings.TrimPrefix(c.Operator, "i"), strings.ToLower(value.(string)))
+		return fmt.Sprintf(`LOWER(%s) LIKE %s ESCAPE '\'`, column, b.arg(pattern)), nil
+	case "exists", "isNull":
+		if value == (c.Operator == "isNull") {
+			return fmt.Sprintf("%s IS NULL", column), nil
+		}
+		return fmt.Sprintf("%s IS NOT NULL", column), nil
+	case "between":
+		bounds := value.([]any)
+		return fmt.Sprintf("%s BETWEEN %s AND %s", column, b.arg(bounds[0]), b.arg(bounds[1])), nil
+	case "within":
+		d, err := parseDuration(value.(string))
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+		}
+		now := time.Now()
+		return fmt.Sprintf("%s BETWEEN %s AND %s", column, b.arg(now.Add(-d)), b.arg(now.Add(d))), nil
+	}
+
+	return "", fmt.Errorf("%w: '%s' can't be translated into SQL", ErrUnsupportedOperator, c.Operator)
+}
+
+// column returns the schema and the column of
+// the field with the given (dotted) path.
+func (b *sqlBuilder) column(path string) (*schemaField, string, error) {
+	field := b.schema
+	var columns []string
+	for _, name := range strings.Split(path, ".") {
+		nested, ok := field.field(name)
+		if !ok {
+			return nil, "", fmt.Errorf("%w: '%s'", ErrUnknownField, path)
+		}
+		field = nested
+		column := field.column
+		if column == "" {
+			column = field.name
+		}
+		if column == "-" {
+			return nil, "", fmt.Errorf("%w: '%s' does not have a column", ErrUnknownField, path)
+		}
+		if b.dialect.QuoteIdentifier != nil {
+			column = b.dialect.QuoteIdentifier(column)
+		}
+		columns = append(columns, column)
+	}
+
+	return field, strings.Join(columns, "."), nil
+}
+
+// arg adds an argument, and returns its placeholder.
+func (b *sqlBuilder) arg(v any) string {
+	b.args = append(b.args, v)
+	return b.dialect.Placeholder(len(b.args))
+}
+
+func (b *sqlBuilder) list(values []any) string {
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = b.arg(v)
+	}
+	return strings.Join(placeholders, ", ")
+}
+
+// sqlValue converts the literal of a comparison into an argument:
+// numbers become an int64 or a float64, and the dates that the
+// values of date fields are compared with become a time.Time.
+func sqlValue(v any, field *schemaField) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case string:
+		if !isTime(field.typ) {
+			return val
+		}
+		t, _, err := parseTime(val, "")
+		if err != nil {
+			return val
+		}
+		return t
+	case []any:
+		values := make([]any, len(val))
+		for i, elem := range val {
+			values[i] = sqlValue(elem, field)
+		}
+		return values
+	}
+
+	return v
+}
+
+// likePattern converts the value of a substring operator
+// into a LIKE pattern, escaping the wildcards of LIKE.
+func likePattern(operator, value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	switch operator {
+	case "startsWith":
+		return value + "%"
+	case "endsWith":
+		return "%" + value
+	}
+	return "%" + value + "%"
+}
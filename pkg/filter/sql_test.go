@@ -0,0 +1,175 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorz"
+)
+
+type sqlTestCase struct {
+	name         string
+	filterJson   string
+	expectedSQL  string
+	expectedArgs []any
+}
+
+func TestToSQL(t *testing.T) {
+	cases := []sqlTestCase{
+		{
+			name:         "eq",
+			filterJson:   `{"kind": {"eq": "MOVIE"}}`,
+			expectedSQL:  `"kind" = $1`,
+			expectedArgs: []any{"MOVIE"},
+		},
+		{
+			name:         "db tag",
+			filterJson:   `{"movieYear": {"gte": 1985, "lt": 1990}}`,
+			expectedSQL:  `"movie_year" >= $1 AND "movie_year" < $2`,
+			expectedArgs: []any{int64(1985), int64(1990)},
+		},
+		{
+			name:         "ne includes nulls",
+			filterJson:   `{"tagline": {"ne": "Get busy living"}}`,
+			expectedSQL:  `("tagline" <> $1 OR "tagline" IS NULL)`,
+			expectedArgs: []any{"Get busy living"},
+		},
+		{
+			name:         "in and nin",
+			filterJson:   `{"movieYear": {"in": [1985, 1994]}, "kind": {"nin": ["SERIES"]}}`,
+			expectedSQL:  `("kind" NOT IN ($1) OR "kind" IS NULL) AND "movie_year" IN ($2, $3)`,
+			expectedArgs: []any{"SERIES", int64(1985), int64(1994)},
+		},
+		{
+			name:        "empty in",
+			filterJson:  `{"kind": {"in": []}}`,
+			expectedSQL: `1 = 0`,
+		},
+		{
+			name:         "like patterns are escaped",
+			filterJson:   `{"title": {"contains": "100%_"}, "description": {"istartsWith": "The"}}`,
+			expectedSQL:  `"title" LIKE $1 ESCAPE '\' AND LOWER("description") LIKE $2 ESCAPE '\'`,
+			expectedArgs: []any{`%100\%\_%`, "the%"},
+		},
+		{
+			name:         "ieq",
+			filterJson:   `{"title": {"ieq": "BACK TO THE FUTURE"}}`,
+			expectedSQL:  `LOWER("title") = $1`,
+			expectedArgs: []any{"back to the future"},
+		},
+		{
+			name:         "imatches",
+			filterJson:   `{"title": {"imatches": "^back"}}`,
+			expectedSQL:  `"title" ~ $1`,
+			expectedArgs: []any{"(?i)^back"},
+		},
+		{
+			name:        "exists and isNull",
+			filterJson:  `{"tagline": {"exists": false}, "director": {"isNull": false}}`,
+			expectedSQL: `"tagline" IS NULL AND "director" IS NOT NULL`,
+		},
+		{
+			name:         "between",
+			filterJson:   `{"movieYear": {"between": [1980, 1989]}}`,
+			expectedSQL:  `"movie_year" BETWEEN $1 AND $2`,
+			expectedArgs: []any{int64(1980), int64(1989)},
+		},
+		{
+			name:         "nested fields",
+			filterJson:   `{"director": {"name": {"eq": "Robert Zemeckis"}, "birthYear": {"lt": 1960}}}`,
+			expectedSQL:  `"director"."name" = $1 AND "director"."birth_year" < $2`,
+			expectedArgs: []any{"Robert Zemeckis", int64(1960)},
+		},
+		{
+			name:         "dates",
+			filterJson:   `{"releaseDate": {"before": "1990-01-01"}}`,
+			expectedSQL:  `"release_date" < $1`,
+			expectedArgs: []any{time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:         "or",
+			filterJson:   `{"or": [{"kind": {"eq": "MOVIE"}, "movieYear": {"lt": 1990}}, {"title": {"startsWith": "Back"}}]}`,
+			expectedSQL:  `("kind" = $1 AND "movie_year" < $2) OR "title" LIKE $3 ESCAPE '\'`,
+			expectedArgs: []any{"MOVIE", int64(1990), "Back%"},
+		},
+		{
+			name:         "not",
+			filterJson:   `{"not": {"kind": {"eq": "SERIES"}}}`,
+			expectedSQL:  `NOT ("kind" = $1)`,
+			expectedArgs: []any{"SERIES"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter, err := NewStructFilterE[Movie](tc.filterJson)
+			require.NoError(t, err)
+
+			where, args, err := structFilter.ToSQL(Postgres)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedSQL, where)
+			assert.Equal(t, tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestToSQL_SQLite(t *testing.T) {
+	movieFilter := MovieFilter{
+		Title:     &Operator{Matches: "^Back"},
+		MovieYear: &Operator{In: []any{1985, 1989}},
+	}
+	where, args, err := ToSQL[Movie](movieFilter, SQLite)
+
+	require.NoError(t, err)
+	assert.Equal(t, `"title" REGEXP ? AND "movie_year" IN (?, ?)`, where)
+	assert.Equal(t, []any{"^Back", int64(1985), int64(1989)}, args)
+}
+
+func TestToSQL_QuotedIdentifiers(t *testing.T) {
+	type Item struct {
+		Order int    `json:"order"`
+		Label string `json:"label" db:"user label"`
+	}
+	itemFilter := `{"order": {"gt": 1}, "label": {"eq": "x"}}`
+
+	where, _, err := ToSQL[Item](json.RawMessage(itemFilter), MySQL)
+	require.NoError(t, err)
+	assert.Equal(t, "`order` > ? AND `user label` = ?", where)
+
+	where, _, err = ToSQL[Item](json.RawMessage(itemFilter), Dialect{Placeholder: SQLite.Placeholder})
+	require.NoError(t, err)
+	assert.Equal(t, "order > ? AND user label = ?", where)
+
+	assert.Equal(t, `"say ""hi"""`, Postgres.QuoteIdentifier(`say "hi"`))
+}
+
+func TestToSQL_Within(t *testing.T) {
+	where, args, err := NewStructFilter[Movie](`{"updatedAt": {"within": "7d"}}`).ToSQL(Postgres)
+
+	require.NoError(t, err)
+	assert.Equal(t, `"updatedAt" BETWEEN $1 AND $2`, where)
+	require.Len(t, args, 2)
+	assert.WithinDuration(t, time.Now().Add(-7*24*time.Hour), args[0].(time.Time), time.Minute)
+	assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), args[1].(time.Time), time.Minute)
+}
+
+func TestToSQL_Unsupported(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "quantifier", filterJSON: `{"genres": {"any": {"eq": "Drama"}}}`, err: ErrUnsupportedOperator},
+		{name: "normalize", filterJSON: `{"title": {"eq": "back to the future", "normalize": "unaccent"}}`, err: ErrUnsupportedOperator},
+		{name: "unknown field", filterJSON: `{"producer": {"eq": "Bob Gale"}}`, err: ErrUnknownField},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := ToSQL[Movie](json.RawMessage(tc.filterJSON), Postgres)
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorIs(t, err, errorz.ErrBadRequest)
+		})
+	}
+}
@@ -1,35 +1,124 @@
 package filter
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 
-	"github.com/tartale/go/pkg/jsonx"
-	"github.com/tartale/go/pkg/maps"
-	"github.com/tartale/go/pkg/structs"
+	"github.com/PaesslerAG/gval"
 )
 
+// typeOfOperator is the type of the operator objects of the
+// dynamic struct types of StructFilters; if there are custom
+// operators, it embeds the fields of Operator, and has a field
+// for each of them (see RegisterOperator).
 var typeOfOperator = reflect.TypeFor[*Operator]()
 
+// typeOfEmbeddedOperator is the type of the operator objects that
+// the dynamic struct types of nested structs and lists embed; it
+// is the same as typeOfOperator, except that it has no methods.
+var typeOfEmbeddedOperator = reflect.PointerTo(operatorFields)
+
+// operatorFields has the same fields as Operator, but not its
+// methods; the dynamic struct types embed it instead of Operator,
+// since reflect.StructOf can't embed a type that has methods.
+var operatorFields = func() reflect.Type {
+	t := reflect.TypeFor[Operator]()
+	fields := make([]reflect.StructField, t.NumField())
+	for i := range fields {
+		f := t.Field(i)
+		fields[i] = reflect.StructField{Name: f.Name, Type: f.Type, Tag: f.Tag}
+	}
+
+	return reflect.StructOf(fields)
+}()
+
+// operatorListField is the "and" field of the dynamic struct types
+// that embed Operator; it shadows the field of Operator, whose
+// elements can't have the fields of a nested struct (or the custom
+// operators), so that its elements are kept as they are.
+var operatorListField = reflect.StructField{
+	Name: "And",
+	Type: reflect.TypeFor[[]json.RawMessage](),
+	Tag:  `json:"and,omitempty"`,
+}
+
+// filterTypes caches the dynamic struct type that
+// mirrors each filtered type; see filterType.
+var filterTypes sync.Map
+
 // StructFilter is an object that allows a caller to
 // filter a list of objects by their fields, using a
 // JSON-compatible expression language.
 type StructFilter[T any] struct {
-	Any any
+	Any     any
+	program *program
+}
+
+// program holds the expression of a StructFilter, as parsed
+// with the schema of its type (so that its dates are parsed
+// with the formats of their fields), and its compiled form, so
+// that it is only parsed once, no matter how many items are
+// filtered.
+type program struct {
+	expr      Expr
+	evaluable gval.Evaluable
 }
 
 // NewStructFilter creates a struct filter that mirrors
 // the input type T, and can be used for filtering
 // lists of objects of type T by the fields of T.
 // The inputJson string is the expression that
-// will be used to evaluate inclusion of an item of type T.
+// will be used to evaluate inclusion of an item of type T;
+// it can either be a list of filter objects, or a single
+// filter object. The expression is compiled once, when the
+// filter is created.
 func NewStructFilter[T any](inputJson string) StructFilter[T] {
-	structFilter := newStructFilter[T]()
-	jsonx.MustUnmarshalFromString(inputJson, &structFilter)
+	structFilter, err := NewStructFilterE[T](inputJson)
+	if err != nil {
+		panic(err)
+	}
 
 	return structFilter
 }
 
+// NewStructFilterE is the same as NewStructFilter, but returns
+// an error instead of panicking if the inputJson is not a valid
+// filter for type T. The error is a *PathError that identifies
+// the offending part of the inputJson, and wraps one of the
+// errors of this package (e.g. ErrUnknownField), all of which
+// wrap errorz.ErrBadRequest. The literals of the filter are
+// checked against the kinds of the fields of T (e.g. a numeric
+// field can't be compared with a string); if any of them can't
+// be compared, the error is an errorx.Errors with a *PathError
+// for each of them.
+func NewStructFilterE[T any](inputJson string) (StructFilter[T], error) {
+	structFilter := newStructFilter[T]()
+	filterJson := []byte(inputJson)
+	expr, err := parseExpr(filterJson, parseOptions{schema: schemaOf(reflect.TypeFor[T]())})
+	if err != nil {
+		return StructFilter[T]{}, err
+	}
+	if trimmed := bytes.TrimSpace(filterJson); len(trimmed) > 0 && trimmed[0] == '{' {
+		filterJson = slices.Concat([]byte("["), trimmed, []byte("]"))
+	}
+	err = json.Unmarshal(filterJson, &structFilter)
+	if err != nil {
+		return StructFilter[T]{}, &PathError{Path: "$", Err: fmt.Errorf("%w: %w", ErrInvalidFilter, err)}
+	}
+	err = structFilter.program.compile(expr)
+	if err != nil {
+		return StructFilter[T]{}, err
+	}
+
+	return structFilter, nil
+}
+
 // MarshalJSON overrides the default JSON marshal function
 // so that the inner type is marshalled instead of the
 // StructFilter outer wrapper.
@@ -40,68 +129,295 @@ func (sf StructFilter[T]) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON overrides the default JSON unmarshal function
 // so that the inner type is unmarshalled instead of the
 // StructFilter outer wrapper.
+// The JSON is checked in the same way as by NewStructFilterE,
+// and the filter's expression is recompiled after it is unmarshalled.
 func (sf StructFilter[T]) UnmarshalJSON(data []byte) error {
-	return json.Unmarshal(data, sf.Any)
+	expr, err := parseExpr(data, parseOptions{schema: schemaOf(reflect.TypeFor[T]())})
+	if err != nil {
+		return err
+	}
+	data, err = expandPaths(data)
+	if err != nil {
+		return err
+	}
+	err = unmarshalWithNumbers(data, sf.Any)
+	if err != nil {
+		return err
+	}
+	if sf.program == nil {
+		return nil
+	}
+
+	return sf.program.compile(expr)
 }
 
 // ShouldInclude accepts an object of type T and determines
 // whether it passes the StructFilter for type T.
 func (sf StructFilter[T]) ShouldInclude(val T) bool {
-	expression := GetExpression(sf.Any)
-	structWrapper := structs.New(val)
-	structWrapper.TagName = "json"
-	mapOfValues := structWrapper.Map()
-	mapOfValues = maps.CastPrimitives(mapOfValues)
-	eval := MustEvaluate(expression, mapOfValues)
+	result, err := sf.ShouldIncludeE(val)
+	if err != nil {
+		panic(err)
+	}
+
+	return result
+}
+
+// ShouldIncludeE is the same as ShouldInclude, but returns
+// an error instead of panicking if the filter can't be
+// evaluated against the given value. Evaluation errors
+// are wrapped with ErrTypeMismatch.
+func (sf StructFilter[T]) ShouldIncludeE(val T) (bool, error) {
+	evaluable, err := sf.evaluable()
+	if err != nil {
+		return false, err
+	}
+	values := valueOf(reflect.ValueOf(val))
+	eval, err := evaluable.EvalBool(context.Background(), values)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+	}
+
+	return eval, nil
+}
 
-	return eval.(bool)
+// evaluable returns the compiled expression of the
+// StructFilter; if the StructFilter was not created
+// by NewStructFilter, the expression is compiled on demand.
+func (sf StructFilter[T]) evaluable() (gval.Evaluable, error) {
+	if sf.program != nil && sf.program.evaluable != nil {
+		return sf.program.evaluable, nil
+	}
+	expr, err := sf.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	return Compile(expr)
+}
+
+// expr returns the expression of the StructFilter; if the
+// StructFilter was not created by NewStructFilter, its filter
+// objects are parsed on demand, with the schema of T.
+func (sf StructFilter[T]) expr() (Expr, error) {
+	if sf.program != nil && sf.program.expr != nil {
+		return sf.program.expr, nil
+	}
+	filterJson, err := json.Marshal(sf.Any)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseExpr(filterJson, parseOptions{schema: schemaOf(reflect.TypeFor[T]())})
+}
+
+// compile compiles the given expression into the program.
+func (p *program) compile(expr Expr) error {
+	evaluable, err := Compile(expr)
+	if err != nil {
+		return err
+	}
+	p.expr, p.evaluable = expr, evaluable
+
+	return nil
 }
 
 // newStructFilter creates a struct filter that mirrors
 // the input type T, and can be used for filtering
 // lists of objects of type T by the fields of T.
 func newStructFilter[T any]() StructFilter[T] {
-	// Walk the input struct and make a new struct that
-	// has all same field names, but with the type *Operator
-	// instead of the original type.
-	var newFields []reflect.StructField
-	filterWalkFn := func(filterField reflect.StructField, filterValue reflect.Value) error {
-		newField := filterField
-		newField.Type = typeOfOperator
-		newFields = append(newFields, newField)
-		return nil
+	sliceOfNewStructType := reflect.SliceOf(filterType[T]())
+	structFilter := StructFilter[T]{
+		Any:     reflect.New(sliceOfNewStructType).Interface(),
+		program: &program{},
 	}
-	var t T
-	structWrapper := structs.New(t)
-	structWrapper.TagName = "json"
-	structWrapper.Walk(filterWalkFn)
-	newStructType := reflect.StructOf(newFields)
-	sliceOfNewStructType := reflect.SliceOf(newStructType)
-	// Each StructFilter has two additional fields "And" and "Or"
-	// of type []StructFilter; these provide the ability for
-	// the StructFilter to represent compound boolean expressions.
-	andType := reflect.StructField{
-		Name: "And",
-		Type: sliceOfNewStructType,
-		Tag:  reflect.StructTag(`json:"and,omitempty"`),
-	}
-	orType := reflect.StructField{
-		Name: "Or",
-		Type: sliceOfNewStructType,
-		Tag:  reflect.StructTag(`json:"or,omitempty"`),
-	}
-	// Add the "And" and "Or" fields, and then recreate the
-	// dynamic struct and slice of dynamic struct.
-	newFields = append(newFields, andType, orType)
-	newStructType = reflect.StructOf(newFields)
-	sliceOfNewStructType = reflect.SliceOf(newStructType)
-	// Fixup the "And" and "Or" types to ensure the addition of themselves,
-	// then remake the struct type and slice of one more time
-	newFields[len(newFields)-2].Type = sliceOfNewStructType
-	newFields[len(newFields)-1].Type = sliceOfNewStructType
-	newStructType = reflect.StructOf(newFields)
-	sliceOfNewStructType = reflect.SliceOf(newStructType)
-	structFilter := StructFilter[T]{reflect.New(sliceOfNewStructType).Interface()}
 
 	return structFilter
 }
+
+// filterType returns the dynamic struct type that mirrors
+// the input type T; it has all the same field names as T,
+// but with the type *Operator instead of the original type.
+// A field that is a nested struct (or a pointer to one) has
+// the type of a pointer to a dynamic struct that mirrors the
+// nested struct in the same way, and that embeds the operators, so
+// that the nested struct can also be compared as a whole
+// (e.g. with the "exists" operator); list fields are mirrored
+// in the same way (see fieldFilterType).
+func filterType[T any]() reflect.Type {
+	typeOfT := reflect.TypeFor[T]()
+	if newStructType, ok := filterTypes.Load(typeOfT); ok {
+		return newStructType.(reflect.Type)
+	}
+
+	newFields := filterFields(schemaOf(typeOfT))
+	// Each StructFilter has the additional fields "And", "Or"
+	// and "Not"; these provide the ability for the StructFilter
+	// to represent compound boolean expressions. Because reflect
+	// can't create recursive types, the clauses of these fields
+	// are wrapped in the clause type, which creates the dynamic
+	// struct for each clause when it is unmarshalled.
+	newFields = append(newFields,
+		reflect.StructField{
+			Name: "And",
+			Type: reflect.TypeFor[[]clause[T]](),
+			Tag:  reflect.StructTag(`json:"and,omitempty"`),
+		},
+		reflect.StructField{
+			Name: "Or",
+			Type: reflect.TypeFor[[]clause[T]](),
+			Tag:  reflect.StructTag(`json:"or,omitempty"`),
+		},
+		reflect.StructField{
+			Name: "Not",
+			Type: reflect.TypeFor[*clause[T]](),
+			Tag:  reflect.StructTag(`json:"not,omitempty"`),
+		},
+	)
+	newStructType := reflect.StructOf(newFields)
+	filterTypes.Store(typeOfT, newStructType)
+
+	return newStructType
+}
+
+// filterFields returns the fields of the dynamic
+// struct type that mirrors the given schema.
+func filterFields(schema *schemaField) []reflect.StructField {
+	var newFields []reflect.StructField
+	for _, field := range schema.fields {
+		newFields = append(newFields, reflect.StructField{
+			Name: field.goName,
+			Type: fieldFilterType(field),
+			Tag:  reflect.StructTag(fmt.Sprintf(`json:"%s,omitempty"`, field.name)),
+		})
+	}
+
+	return newFields
+}
+
+// fieldFilterType returns the type of the field of the dynamic
+// struct type that mirrors the given field. The type of a primitive
+// field is *Operator; the type of a nested struct or a list field is a
+// pointer to a dynamic struct that embeds the operators, and that also has
+// either the fields of the nested struct, or the quantifiers of the
+// list, with the type that mirrors the elements of the list.
+func fieldFilterType(field *schemaField) reflect.Type {
+	if len(field.fields) == 0 && field.elem == nil {
+		return typeOfOperator
+	}
+	newFields := []reflect.StructField{{
+		Name:      "Operator",
+		Type:      typeOfEmbeddedOperator,
+		Anonymous: true,
+	}}
+	newFields = append(newFields, operatorListField)
+	newFields = append(newFields, filterFields(field)...)
+	if field.elem != nil {
+		elemType := fieldFilterType(field.elem)
+		newFields = append(newFields,
+			reflect.StructField{Name: "Any", Type: elemType, Tag: `json:"any,omitempty"`},
+			reflect.StructField{Name: "All", Type: elemType, Tag: `json:"all,omitempty"`},
+			reflect.StructField{Name: "None", Type: elemType, Tag: `json:"none,omitempty"`},
+			reflect.StructField{Name: "Size", Type: typeOfOperator, Tag: `json:"size,omitempty"`},
+		)
+	}
+
+	return reflect.PointerTo(reflect.StructOf(newFields))
+}
+
+// clause is a nested clause of a StructFilter for
+// type T, i.e. an element of its "and" or "or" fields,
+// or the value of its "not" field.
+type clause[T any] struct {
+	Any any
+}
+
+// MarshalJSON marshals the inner dynamic struct
+// instead of the clause wrapper.
+func (c clause[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Any)
+}
+
+// UnmarshalJSON creates the dynamic struct that
+// mirrors type T, and unmarshals the clause into it.
+func (c *clause[T]) UnmarshalJSON(data []byte) error {
+	c.Any = reflect.New(filterType[T]()).Interface()
+	return unmarshalWithNumbers(data, c.Any)
+}
+
+// unmarshalWithNumbers unmarshals the JSON representation of a
+// filter into v; the numbers of the filter are unmarshalled as
+// json.Numbers rather than as float64s, so that large integers
+// (e.g. 1<<53 + 1) keep their precision when the filter is
+// marshalled again.
+func unmarshalWithNumbers(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(v)
+}
+
+// expandPaths rewrites the dotted paths in the keys of a filter
+// into nested objects, so that the filter can be unmarshalled
+// into the dynamic struct type that mirrors the nesting of the
+// filtered type; for example, this:
+//
+//	`{"director.name": {"eq": "Robert Zemeckis"}}`
+//
+// becomes:
+//
+//	`{"director": {"name": {"eq": "Robert Zemeckis"}}}`
+func expandPaths(data []byte) ([]byte, error) {
+	if !bytes.Contains(data, []byte(".")) {
+		return data, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var filter any
+	err := decoder.Decode(&filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(expandPathsOf(filter))
+}
+
+func expandPathsOf(v any) any {
+	switch val := v.(type) {
+	case []any:
+		for i, elem := range val {
+			val[i] = expandPathsOf(elem)
+		}
+	case map[string]any:
+		expanded := map[string]any{}
+		for key, elem := range val {
+			mergePath(expanded, strings.Split(key, "."), expandPathsOf(elem))
+		}
+		return expanded
+	}
+
+	return v
+}
+
+// mergePath sets the value at the given path of nested objects,
+// merging it with any objects that are already at that path.
+func mergePath(m map[string]any, path []string, v any) {
+	key := path[0]
+	if len(path) > 1 {
+		nested, ok := m[key].(map[string]any)
+		if !ok {
+			nested = map[string]any{}
+			m[key] = nested
+		}
+		mergePath(nested, path[1:], v)
+		return
+	}
+	existing, ok := m[key].(map[string]any)
+	incoming, ok2 := v.(map[string]any)
+	if !ok || !ok2 {
+		m[key] = v
+		return
+	}
+	for k, elem := range incoming {
+		mergePath(existing, []string{k}, elem)
+	}
+}
@@ -0,0 +1,89 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/tartale/go/pkg/maps"
+	"github.com/tartale/go/pkg/structs"
+)
+
+const benchmarkFilterJson = `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"gte": 1980}}, {"and": [{"title": {"matches": "Back to the .*"}}]}]}]`
+
+func makeMovies(n int) []Movie {
+	movies := make([]Movie, n)
+	for i := range movies {
+		movie := testMovieList[i%len(testMovieList)]
+		movie.Title = fmt.Sprintf("%s %d", movie.Title, i)
+		movie.MovieYear = 1950 + i%70
+		movies[i] = movie
+	}
+	return movies
+}
+
+// BenchmarkShouldInclude measures the evaluation of a
+// single item with an expression that is compiled once.
+func BenchmarkShouldInclude(b *testing.B) {
+	structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		structFilter.ShouldInclude(testMovie)
+	}
+}
+
+// BenchmarkShouldInclude_Uncompiled measures the evaluation
+// of a single item when the expression is regenerated and
+// re-parsed for every item.
+func BenchmarkShouldInclude_Uncompiled(b *testing.B) {
+	structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		expression := GetExpression(structFilter.Any)
+		structWrapper := structs.New(testMovie)
+		structWrapper.TagName = "json"
+		mapOfValues := maps.CastPrimitives(structWrapper.Map())
+		MustEvaluate(expression, mapOfValues)
+	}
+}
+
+func BenchmarkFilterAllFor(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		movies := makeMovies(n)
+		b.Run(fmt.Sprintf("compiled/%d", n), func(b *testing.B) {
+			structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				FilterAllFor(structFilter, movies)
+			}
+		})
+		b.Run(fmt.Sprintf("uncompiled/%d", n), func(b *testing.B) {
+			structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+			// Clearing the compiled program forces the expression
+			// to be compiled on demand for every item.
+			structFilter.program = nil
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				FilterAllFor(structFilter, movies)
+			}
+		})
+	}
+}
+
+func BenchmarkFilterAllParallelForE(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		movies := makeMovies(n)
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			structFilter := NewStructFilter[Movie](benchmarkFilterJson)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				FilterAllParallelForE(context.Background(), structFilter, movies, 0)
+			}
+		})
+	}
+}
@@ -1,9 +1,15 @@
 package filter
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorx"
+	"github.com/tartale/go/pkg/errorz"
+	"github.com/tartale/go/pkg/jsontime"
 	"github.com/tartale/go/pkg/jsonx"
 )
 
@@ -85,7 +91,7 @@ func TestShouldInclude_ExplicitLogicalAndFilter(t *testing.T) {
 }
 
 func TestShouldInclude_ExplicitLogicalOrFilter(t *testing.T) {
-	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"title": {"matches": "Back to the .*"}}]}]`
+	structFilterJson := `{"or": [{"movieYear": {"eq": 1955}}, {"title": {"matches": "Back to the .*"}}]}`
 	structFilter := NewStructFilter[Movie](structFilterJson)
 
 	result := structFilter.ShouldInclude(testMovie)
@@ -94,7 +100,7 @@ func TestShouldInclude_ExplicitLogicalOrFilter(t *testing.T) {
 }
 
 func TestShouldInclude_ComplexNestedFilter(t *testing.T) {
-	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"eq": 1985}}, {"and": [{"title": {"eq": "Back to the Future"}}]}]}]`
+	structFilterJson := `[{"title": {"matches": "Back to the .*"}}, {"or": [{"movieYear": {"eq": 1955}}, {"and": [{"movieYear": {"eq": 1985}}, {"kind": {"eq": "MOVIE"}}]}]}]`
 	structFilter := NewStructFilter[Movie](structFilterJson)
 
 	result := structFilter.ShouldInclude(testMovie)
@@ -147,7 +153,16 @@ func TestShouldNotInclude_ExplicitLogicalAndFilter(t *testing.T) {
 }
 
 func TestShouldNotInclude_ExplicitLogicalOrFilter(t *testing.T) {
-	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"title": {"matches": "The Shawshank .*"}}]}]`
+	structFilterJson := `{"or": [{"movieYear": {"eq": 1955}}, {"title": {"matches": "The Shawshank .*"}}]}`
+	structFilter := NewStructFilter[Movie](structFilterJson)
+
+	result := structFilter.ShouldInclude(testMovie)
+
+	assert.False(t, result)
+}
+
+func TestShouldNotInclude_ExplicitLogicalOrFilterWithFalseSibling(t *testing.T) {
+	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"title": {"matches": "Back to the .*"}}, {"kind": {"eq": "SERIES"}}]}]`
 	structFilter := NewStructFilter[Movie](structFilterJson)
 
 	result := structFilter.ShouldInclude(testMovie)
@@ -156,7 +171,7 @@ func TestShouldNotInclude_ExplicitLogicalOrFilter(t *testing.T) {
 }
 
 func TestShouldNotInclude_ComplexNestedFilter(t *testing.T) {
-	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"eq": 1985}}, {"and": [{"title": {"eq": "The Shawshank Redemption"}}]}]}]`
+	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"eq": 1985}}, {"and": [{"title": {"eq": "Back to the Future"}}]}]}]`
 	structFilter := NewStructFilter[Movie](structFilterJson)
 
 	result := structFilter.ShouldInclude(testMovie)
@@ -164,7 +179,7 @@ func TestShouldNotInclude_ComplexNestedFilter(t *testing.T) {
 }
 
 func TestFilterAllFor(t *testing.T) {
-	structFilterJson := `[{"movieYear": {"eq": 1955}}, {"or": [{"movieYear": {"eq": 1985}}, {"and": [{"title": {"eq": "Back to the Future"}}]}]}]`
+	structFilterJson := `{"or": [{"movieYear": {"eq": 1955}}, {"and": [{"movieYear": {"eq": 1985}}, {"title": {"eq": "Back to the Future"}}]}]}`
 	structFilter := NewStructFilter[Movie](structFilterJson)
 
 	result := FilterAllFor(structFilter, testMovieList)
@@ -172,3 +187,800 @@ func TestFilterAllFor(t *testing.T) {
 	assert.Len(t, result, 1)
 	assert.Equal(t, "Back to the Future", result[0].Title)
 }
+
+func TestShouldInclude_FieldNamesOfKeywords(t *testing.T) {
+	type Flags struct {
+		True  int `json:"true"`
+		Count int `json:"first-count"`
+	}
+	structFilter := NewStructFilter[Flags](`{"true": {"eq": 2}, "first-count": {"gt": 0}}`)
+
+	assert.False(t, structFilter.ShouldInclude(Flags{True: 1, Count: 1}))
+	assert.True(t, structFilter.ShouldInclude(Flags{True: 2, Count: 1}))
+}
+
+func TestShouldInclude_RecompiledAfterUnmarshal(t *testing.T) {
+	structFilter := NewStructFilter[Movie](`[{"kind": {"eq": "SERIES"}}]`)
+	assert.False(t, structFilter.ShouldInclude(testMovie))
+
+	jsonx.MustUnmarshalFromString(`[{"kind": {"eq": "MOVIE"}}]`, &structFilter)
+
+	assert.True(t, structFilter.ShouldInclude(testMovie))
+}
+
+func TestShouldInclude_ZeroValueProgram(t *testing.T) {
+	structFilter := NewStructFilter[Movie](`[{"kind": {"eq": "MOVIE"}}]`)
+	structFilter.program = nil
+
+	assert.True(t, structFilter.ShouldInclude(testMovie))
+}
+
+func TestNewStructFilterE_UnknownField(t *testing.T) {
+	structFilterJson := `[{"kind": {"eq": "MOVIE"}}, {"or": [{"producer": {"eq": "Bob Gale"}}]}]`
+
+	_, err := NewStructFilterE[Movie](structFilterJson)
+
+	assert.ErrorIs(t, err, ErrUnknownField)
+	assert.ErrorIs(t, err, errorz.ErrBadRequest)
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, "$[1].or[0].producer", pathErr.Path)
+}
+
+func TestNewStructFilterE_InvalidOperator(t *testing.T) {
+	structFilterJson := `[{"kind": {"like": "MOVIE"}}]`
+
+	_, err := NewStructFilterE[Movie](structFilterJson)
+
+	assert.ErrorIs(t, err, ErrInvalidOperator)
+	var pathErr *PathError
+	require.ErrorAs(t, err, &pathErr)
+	assert.Equal(t, "$[0].kind.like", pathErr.Path)
+}
+
+func TestNewStructFilterE_InvalidRegex(t *testing.T) {
+	structFilterJson := `[{"title": {"matches": "Back to the ("}}]`
+
+	_, err := NewStructFilterE[Movie](structFilterJson)
+
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+	assert.ErrorIs(t, err, errorz.ErrBadRequest)
+}
+
+func TestNewStructFilter_PanicsOnError(t *testing.T) {
+	assert.Panics(t, func() {
+		NewStructFilter[Movie](`[{"producer": {"eq": "Bob Gale"}}]`)
+	})
+}
+
+func TestFilterAllForE(t *testing.T) {
+	structFilterJson := `[{"movieYear": {"gte": 1990}}]`
+	structFilter, err := NewStructFilterE[Movie](structFilterJson)
+	require.NoError(t, err)
+
+	result, err := FilterAllForE(structFilter, testMovieList)
+
+	require.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, "The Shawshank Redemption", result[0].Title)
+}
+
+type operatorTestCase struct {
+	name           string
+	filterJson     string
+	expectedTitles []string
+}
+
+func TestFilterAllFor_Operators(t *testing.T) {
+	cases := []operatorTestCase{
+		{
+			name:           "in",
+			filterJson:     `[{"movieYear": {"in": [1955, 1985, 2015]}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "in with enum",
+			filterJson:     `[{"kind": {"in": ["MOVIE", "SERIES"]}}]`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+		{
+			name:           "nin",
+			filterJson:     `[{"movieYear": {"nin": [1955, 1985, 2015]}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "contains",
+			filterJson:     `[{"description": {"contains": "Morgan Freeman"}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "and in a quantifier",
+			filterJson:     `[{"cast": {"any": {"and": [{"name": {"ne": "Tim Robbins"}}, {"name": {"ne": "Morgan Freeman"}}]}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "startsWith",
+			filterJson:     `[{"title": {"startsWith": "Back to"}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "endsWith",
+			filterJson:     `[{"title": {"endsWith": "Redemption"}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "exists",
+			filterJson:     `[{"tagline": {"exists": true}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "not exists",
+			filterJson:     `[{"tagline": {"exists": false}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "isNull",
+			filterJson:     `[{"tagline": {"isNull": true}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "between",
+			filterJson:     `[{"movieYear": {"between": [1990, 1999]}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "between is inclusive",
+			filterJson:     `[{"movieYear": {"between": [1985, 1994]}}]`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+		{
+			name:           "combined operators",
+			filterJson:     `[{"title": {"contains": "the"}, "movieYear": {"between": [1980, 1989]}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter := NewStructFilter[Movie](tc.filterJson)
+
+			result := FilterAllFor(structFilter, testMovieList)
+
+			var titles []string
+			for _, movie := range result {
+				titles = append(titles, movie.Title)
+			}
+			assert.Equal(t, tc.expectedTitles, titles)
+		})
+	}
+}
+
+func TestShouldInclude_OperatorsOnHandWrittenFilter(t *testing.T) {
+	movieFilter := MovieFilter{
+		Title:     &Operator{StartsWith: "Back"},
+		MovieYear: &Operator{In: []any{1985, 1989, 1990}},
+	}
+
+	result, err := ShouldIncludeE(movieFilter, testMovie)
+
+	require.NoError(t, err)
+	assert.True(t, result)
+}
+
+func TestShouldInclude_CaseInsensitive(t *testing.T) {
+	cases := []filterTestCase{
+		{
+			name:          "ieq",
+			filterJSON:    `[{"title": {"ieq": "BACK TO THE FUTURE"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "ine",
+			filterJSON:    `[{"title": {"ine": "back to the future"}}]`,
+			shouldInclude: false,
+		},
+		{
+			name:          "icontains",
+			filterJSON:    `[{"description": {"icontains": "marty mcfly"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "istartsWith",
+			filterJSON:    `[{"title": {"istartsWith": "back TO"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "iendsWith",
+			filterJSON:    `[{"title": {"iendsWith": "FUTURE"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "imatches",
+			filterJSON:    `[{"title": {"imatches": "^back to the .*"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "enum",
+			filterJSON:    `[{"kind": {"ieq": "movie"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "case-sensitive eq",
+			filterJSON:    `[{"title": {"eq": "BACK TO THE FUTURE"}}]`,
+			shouldInclude: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter := NewStructFilter[Movie](tc.filterJSON)
+
+			result := structFilter.ShouldInclude(testMovie)
+
+			assert.Equal(t, tc.shouldInclude, result)
+		})
+	}
+}
+
+func TestShouldInclude_Normalized(t *testing.T) {
+	movie := Movie{Kind: MOVIE, Title: "Le Fabuleux Destin d'Amélie Poulain", MovieYear: 2001}
+	cases := []filterTestCase{
+		{
+			name:          "unaccented case-insensitive",
+			filterJSON:    `[{"title": {"icontains": "amelie", "normalize": "unaccent"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "unaccented case-sensitive",
+			filterJSON:    `[{"title": {"contains": "Amelie", "normalize": "unaccent"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "not normalized",
+			filterJSON:    `[{"title": {"icontains": "amelie"}}]`,
+			shouldInclude: false,
+		},
+		{
+			name:          "decomposed literal",
+			filterJSON:    `[{"title": {"contains": "Ame\u0301lie", "normalize": "NFC"}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "decomposed literal not normalized",
+			filterJSON:    `[{"title": {"contains": "Ame\u0301lie"}}]`,
+			shouldInclude: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter := NewStructFilter[Movie](tc.filterJSON)
+
+			result := structFilter.ShouldInclude(movie)
+
+			assert.Equal(t, tc.shouldInclude, result)
+		})
+	}
+}
+
+func TestNewStructFilter_NotFilter(t *testing.T) {
+	structFilterJsonIn := `[{"kind":{"eq":"MOVIE"}},{"not":{"movieYear":{"eq":1955},"or":[{"title":{"eq":"BacktotheFuture"}}]}}]`
+	structFilter := NewStructFilter[Movie](structFilterJsonIn)
+	structFilterJsonOut := jsonx.MustMarshalToString(structFilter)
+
+	assert.Equal(t, structFilterJsonIn, structFilterJsonOut)
+}
+
+func TestNewStructFilter_DeeplyNestedFilter(t *testing.T) {
+	structFilterJsonIn := `[{"or":[{"and":[{"or":[{"and":[{"not":{"or":[{"and":[{"movieYear":{"eq":1955}}]}]}}]}]}]}]}]`
+	structFilter := NewStructFilter[Movie](structFilterJsonIn)
+	structFilterJsonOut := jsonx.MustMarshalToString(structFilter)
+
+	assert.Equal(t, structFilterJsonIn, structFilterJsonOut)
+}
+
+func TestShouldInclude_NotFilter(t *testing.T) {
+	cases := []filterTestCase{
+		{
+			name:          "simple 'not' should include",
+			filterJSON:    `[{"not": {"kind": {"eq": "SERIES"}}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "simple 'not' should exclude",
+			filterJSON:    `[{"not": {"kind": {"eq": "MOVIE"}}}]`,
+			shouldInclude: false,
+		},
+		{
+			name:          "double 'not' should include",
+			filterJSON:    `[{"not": {"not": {"kind": {"eq": "MOVIE"}}}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "'not' with implied 'and' should include",
+			filterJSON:    `[{"not": {"kind": {"eq": "MOVIE"}, "movieYear": {"eq": 1955}}}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "'not' of 'or' should exclude",
+			filterJSON:    `[{"not": {"or": [{"movieYear": {"eq": 1955}}, {"kind": {"eq": "MOVIE"}}]}}]`,
+			shouldInclude: false,
+		},
+		{
+			name:          "'and' or 'not' should include",
+			filterJSON:    `{"or": [{"and": [{"kind": {"eq": "SERIES"}}]}, {"or": [{"not": {"movieYear": {"eq": 1955}}}]}]}`,
+			shouldInclude: true,
+		},
+		{
+			name:          "'and' or 'not' should exclude",
+			filterJSON:    `{"or": [{"and": [{"kind": {"eq": "SERIES"}}]}, {"or": [{"not": {"movieYear": {"eq": 1985}}}]}]}`,
+			shouldInclude: false,
+		},
+		{
+			name:          "deeply nested 'not' should include",
+			filterJSON:    `[{"or": [{"kind": {"eq": "SERIES"}}, {"and": [{"or": [{"and": [{"not": {"movieYear": {"eq": 1955}}}]}]}]}]}]`,
+			shouldInclude: true,
+		},
+		{
+			name:          "deeply nested 'not' should exclude",
+			filterJSON:    `[{"kind": {"eq": "SERIES"}}, {"or": [{"and": [{"or": [{"and": [{"not": {"movieYear": {"eq": 1985}}}]}]}]}]}]`,
+			shouldInclude: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter := NewStructFilter[Movie](tc.filterJSON)
+
+			result := structFilter.ShouldInclude(testMovie)
+
+			assert.Equal(t, tc.shouldInclude, result)
+		})
+	}
+}
+
+func TestFilterAllFor_NestedFields(t *testing.T) {
+	cases := []operatorTestCase{
+		{
+			name:           "nested object",
+			filterJson:     `[{"director": {"name": {"eq": "Robert Zemeckis"}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "dotted path",
+			filterJson:     `[{"director.name": {"eq": "Robert Zemeckis"}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "nested dotted path",
+			filterJson:     `[{"director": {"name": {"startsWith": "Robert"}, "birthYear": {"lt": 1960}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "nested struct value",
+			filterJson:     `[{"studio.country": {"eq": "US"}}]`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+		{
+			name:           "nested and dotted paths are merged",
+			filterJson:     `[{"studio": {"country": {"eq": "US"}}, "studio.name": {"contains": "Castle"}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "operator on a nested struct",
+			filterJson:     `[{"director": {"exists": false}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "nil pointer to a nested struct",
+			filterJson:     `[{"director.birthYear": {"gt": 1900}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "'not' of a nil pointer to a nested struct",
+			filterJson:     `[{"not": {"director.name": {"eq": "Robert Zemeckis"}}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "nested field in 'or'",
+			filterJson:     `[{"or": [{"director.name": {"eq": "Frank Darabont"}}, {"studio": {"name": {"startsWith": "Castle"}}}]}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter, err := NewStructFilterE[Movie](tc.filterJson)
+			require.NoError(t, err)
+
+			result, err := FilterAllForE(structFilter, testMovieList)
+			require.NoError(t, err)
+
+			var titles []string
+			for _, movie := range result {
+				titles = append(titles, movie.Title)
+			}
+			assert.Equal(t, tc.expectedTitles, titles)
+		})
+	}
+}
+
+func TestNewStructFilter_NestedFilter(t *testing.T) {
+	structFilterJsonIn := `[{"director.name":{"eq":"Robert Zemeckis"}},{"or":[{"director":{"exists":false,"birthYear":{"gt":1950}}}]}]`
+	structFilter := NewStructFilter[Movie](structFilterJsonIn)
+	structFilterJsonOut := jsonx.MustMarshalToString(structFilter)
+
+	assert.Equal(t, `[{"director":{"name":{"eq":"Robert Zemeckis"}}},{"or":[{"director":{"exists":false,"birthYear":{"gt":1950}}}]}]`, structFilterJsonOut)
+}
+
+func TestNewStructFilterE_UnknownNestedField(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "nested object", filterJSON: `[{"director": {"nationality": {"eq": "US"}}}]`, err: ErrUnknownField, path: "$[0].director.nationality"},
+		{name: "dotted path", filterJSON: `[{"studio.address": {"eq": "Hollywood"}}]`, err: ErrUnknownField, path: "$[0].studio.address"},
+		{name: "path through a primitive", filterJSON: `[{"title.length": {"gt": 10}}]`, err: ErrUnknownField, path: "$[0].title.length"},
+		{name: "operator on a primitive", filterJSON: `[{"title": {"length": {"gt": 10}}}]`, err: ErrInvalidOperator, path: "$[0].title.length"},
+		{name: "empty path segment", filterJSON: `[{"director..name": {"eq": "Robert Zemeckis"}}]`, err: ErrUnknownField, path: "$[0].director..name"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewStructFilterE[Movie](tc.filterJSON)
+
+			assert.ErrorIs(t, err, tc.err)
+			var pathErr *PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, tc.path, pathErr.Path)
+		})
+	}
+}
+
+func TestShouldInclude_RecursiveType(t *testing.T) {
+	type Employee struct {
+		Name    string    `json:"name"`
+		Manager *Employee `json:"manager"`
+	}
+	employee := Employee{Name: "Marty", Manager: &Employee{Name: "Doc"}}
+	structFilter := NewStructFilter[Employee](`{"name": {"eq": "Marty"}, "manager": {"exists": true}}`)
+
+	assert.True(t, structFilter.ShouldInclude(employee))
+	assert.False(t, structFilter.ShouldInclude(*employee.Manager))
+}
+
+func TestFilterAllFor_Quantifiers(t *testing.T) {
+	cases := []operatorTestCase{
+		{
+			name:           "any",
+			filterJson:     `[{"genres": {"any": {"eq": "Comedy"}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "any with multiple operators",
+			filterJson:     `[{"genres": {"any": {"startsWith": "Dr", "endsWith": "ma"}}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "all",
+			filterJson:     `[{"genres": {"all": {"in": ["Adventure", "Comedy", "Sci-Fi"]}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "none",
+			filterJson:     `[{"genres": {"none": {"ieq": "comedy"}}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "size",
+			filterJson:     `[{"genres": {"size": {"gt": 2}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "any on a list of structs",
+			filterJson:     `[{"cast": {"any": {"name": {"eq": "Morgan Freeman"}}}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "all on a list of structs",
+			filterJson:     `[{"cast": {"all": {"birthYear": {"lt": 1960}}}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "none on a list of structs",
+			filterJson:     `[{"cast": {"none": {"name": {"contains": "Fox"}}}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "any with multiple fields of the same element",
+			filterJson:     `[{"cast": {"any": {"name": {"startsWith": "L"}, "birthYear": {"eq": 1961}}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "size and any",
+			filterJson:     `[{"or": [{"cast": {"size": {"between": [2, 2]}}}, {"genres": {"any": {"eq": "Sci-Fi"}}}]}]`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+		{
+			name:           "'not' of any",
+			filterJson:     `[{"not": {"genres": {"any": {"eq": "Drama"}}}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter, err := NewStructFilterE[Movie](tc.filterJson)
+			require.NoError(t, err)
+
+			result, err := FilterAllForE(structFilter, testMovieList)
+			require.NoError(t, err)
+
+			var titles []string
+			for _, movie := range result {
+				titles = append(titles, movie.Title)
+			}
+			assert.Equal(t, tc.expectedTitles, titles)
+		})
+	}
+}
+
+func TestShouldInclude_QuantifiersOnEmptyList(t *testing.T) {
+	assert.False(t, NewStructFilter[Movie](`{"genres": {"any": {"eq": "Drama"}}}`).ShouldInclude(testMovie))
+	assert.True(t, NewStructFilter[Movie](`{"genres": {"all": {"eq": "Drama"}}}`).ShouldInclude(testMovie))
+	assert.True(t, NewStructFilter[Movie](`{"genres": {"none": {"eq": "Drama"}}}`).ShouldInclude(testMovie))
+	assert.True(t, NewStructFilter[Movie](`{"genres": {"size": {"eq": 0}}}`).ShouldInclude(testMovie))
+}
+
+func TestNewStructFilter_QuantifierFilter(t *testing.T) {
+	structFilterJsonIn := `[{"genres":{"any":{"eq":"Comedy"},"size":{"gt":2}},"cast":{"all":{"name":{"ne":"Biff Tannen"}}}}]`
+	structFilter := NewStructFilter[Movie](structFilterJsonIn)
+	structFilterJsonOut := jsonx.MustMarshalToString(structFilter)
+
+	assert.Equal(t, structFilterJsonIn, structFilterJsonOut)
+}
+
+func TestNewStructFilterE_InvalidQuantifier(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "quantifier on a primitive", filterJSON: `[{"title": {"any": {"eq": "Back"}}}]`, err: ErrTypeMismatch, path: "$[0].title.any"},
+		{name: "unknown field of the elements", filterJSON: `[{"cast": {"any": {"age": {"gt": 50}}}}]`, err: ErrUnknownField, path: "$[0].cast.any.age"},
+		{name: "field of a primitive element", filterJSON: `[{"genres": {"any": {"name": {"eq": "Comedy"}}}}]`, err: ErrInvalidOperator, path: "$[0].genres.any.name"},
+		{name: "field of the size", filterJSON: `[{"cast": {"size": {"name": {"eq": 2}}}}]`, err: ErrInvalidOperator, path: "$[0].cast.size.name"},
+		{name: "quantifier with a value", filterJSON: `[{"genres": {"any": "Comedy"}}]`, err: ErrInvalidFilter, path: "$[0].genres.any"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewStructFilterE[Movie](tc.filterJSON)
+
+			assert.ErrorIs(t, err, tc.err)
+			var pathErr *PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, tc.path, pathErr.Path)
+		})
+	}
+}
+
+func TestFilterAllFor_Dates(t *testing.T) {
+	cases := []operatorTestCase{
+		{
+			name:           "gt with the format of the field",
+			filterJson:     `[{"releaseDate": {"gt": "1990-01-01"}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "eq",
+			filterJson:     `[{"releaseDate": {"eq": "1985-07-03"}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "eq with an RFC 3339 date",
+			filterJson:     `[{"releaseDate": {"eq": "1994-09-23T00:00:00Z"}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "in",
+			filterJson:     `[{"releaseDate": {"in": ["1955-11-05", "1985-07-03"]}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "between",
+			filterJson:     `[{"releaseDate": {"between": ["1985-01-01", "1985-12-31"]}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "before",
+			filterJson:     `[{"releaseDate": {"before": "1990-01-01"}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "after a relative date",
+			filterJson:     `[{"updatedAt": {"after": "now-7d"}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "before a relative date",
+			filterJson:     `[{"updatedAt": {"before": "now-2w"}}]`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "lte now",
+			filterJson:     `[{"updatedAt": {"lte": "now"}}]`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+		{
+			name:           "within",
+			filterJson:     `[{"updatedAt": {"within": "72h"}}]`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "within days",
+			filterJson:     `[{"updatedAt": {"within": "31d"}}]`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter, err := NewStructFilterE[Movie](tc.filterJson)
+			require.NoError(t, err)
+
+			result, err := FilterAllForE(structFilter, testMovieList)
+			require.NoError(t, err)
+
+			var titles []string
+			for _, movie := range result {
+				titles = append(titles, movie.Title)
+			}
+			assert.Equal(t, tc.expectedTitles, titles)
+		})
+	}
+}
+
+func TestFilterAllFor_DateFormat(t *testing.T) {
+	type Event struct {
+		Name string         `json:"name"`
+		When jsontime.Time  `json:"when" format:"01/02/2006"`
+		Due  *jsontime.Time `json:"due" format:"01/02/2006"`
+	}
+	events := []Event{
+		{Name: "launch", When: *jsontime.New(time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC)), Due: jsontime.New(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))},
+		{Name: "review", When: *jsontime.New(time.Date(2020, 2, 20, 0, 0, 0, 0, time.UTC))},
+	}
+	structFilter, err := NewStructFilterE[Event](`{"when": {"gt": "01/15/2020"}}`)
+	require.NoError(t, err)
+
+	result, err := FilterAllForE(structFilter, events)
+	require.NoError(t, err)
+	assert.Equal(t, events[1:], result)
+
+	structFilter = NewStructFilter[Event](`{"due": {"before": "04/01/2020"}}`)
+	assert.Equal(t, events[:1], FilterAllFor(structFilter, events))
+	assert.True(t, structFilter.Explain(events[0]).Result)
+
+	var request struct {
+		Filter StructFilter[Event] `json:"filter"`
+	}
+	request.Filter = newStructFilter[Event]()
+	require.NoError(t, json.Unmarshal([]byte(`{"filter": [{"when": {"lt": "01/15/2020"}}]}`), &request))
+	assert.Equal(t, events[:1], FilterAllFor(request.Filter, events))
+}
+
+func TestNewStructFilterE_InvalidDate(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "not a date", filterJSON: `[{"releaseDate": {"gt": "yesterday"}}]`, err: ErrTypeMismatch, path: "$[0].releaseDate.gt"},
+		{name: "not in the format of the field", filterJSON: `[{"releaseDate": {"eq": "07/03/1985"}}]`, err: ErrTypeMismatch, path: "$[0].releaseDate.eq"},
+		{name: "number", filterJSON: `[{"updatedAt": {"gt": 1985}}]`, err: ErrTypeMismatch, path: "$[0].updatedAt.gt"},
+		{name: "invalid date in a list", filterJSON: `[{"releaseDate": {"in": ["1985-07-03", "soon"]}}]`, err: ErrTypeMismatch, path: "$[0].releaseDate.in"},
+		{name: "invalid relative date", filterJSON: `[{"updatedAt": {"after": "now-7 days"}}]`, err: ErrTypeMismatch, path: "$[0].updatedAt.after"},
+		{name: "invalid duration", filterJSON: `[{"updatedAt": {"within": "3 days"}}]`, err: ErrTypeMismatch, path: "$[0].updatedAt.within"},
+		{name: "before on a field that is not a date", filterJSON: `[{"title": {"before": "1990-01-01"}}]`, err: ErrTypeMismatch, path: "$[0].title.before"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewStructFilterE[Movie](tc.filterJSON)
+
+			assert.ErrorIs(t, err, tc.err)
+			var pathErr *PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, tc.path, pathErr.Path)
+		})
+	}
+}
+
+func TestNewStructFilterE_LiteralTypes(t *testing.T) {
+	cases := []exprErrorTestCase{
+		{name: "string for a number", filterJSON: `[{"movieYear": {"eq": "nineteen"}}]`, err: ErrTypeMismatch, path: "$[0].movieYear.eq"},
+		{name: "fraction for an integer", filterJSON: `[{"movieYear": {"gt": 1985.5}}]`, err: ErrTypeMismatch, path: "$[0].movieYear.gt"},
+		{name: "number for a string", filterJSON: `[{"title": {"ne": 1985}}]`, err: ErrTypeMismatch, path: "$[0].title.ne"},
+		{name: "boolean for a string", filterJSON: `[{"kind": {"in": ["MOVIE", true]}}]`, err: ErrTypeMismatch, path: "$[0].kind.in"},
+		{name: "string in a range", filterJSON: `[{"movieYear": {"between": [1980, "1990"]}}]`, err: ErrTypeMismatch, path: "$[0].movieYear.between"},
+		{name: "substring of a number", filterJSON: `[{"movieYear": {"contains": "98"}}]`, err: ErrTypeMismatch, path: "$[0].movieYear.contains"},
+		{name: "pattern of a date", filterJSON: `[{"releaseDate": {"matches": "^1985"}}]`, err: ErrTypeMismatch, path: "$[0].releaseDate.matches"},
+		{name: "nested field", filterJSON: `[{"director": {"birthYear": {"lt": "1960"}}}]`, err: ErrTypeMismatch, path: "$[0].director.birthYear.lt"},
+		{name: "list element", filterJSON: `[{"genres": {"any": {"eq": 1}}}]`, err: ErrTypeMismatch, path: "$[0].genres.any.eq"},
+		{name: "size of a list", filterJSON: `[{"cast": {"size": {"gt": "two"}}}]`, err: ErrTypeMismatch, path: "$[0].cast.size.gt"},
+		{name: "invalid pattern", filterJSON: `[{"title": {"matches": "Back to the ("}}]`, err: ErrInvalidPattern, path: "$[0].title.matches"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewStructFilterE[Movie](tc.filterJSON)
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.ErrorIs(t, err, errorz.ErrBadRequest)
+			var pathErr *PathError
+			require.ErrorAs(t, err, &pathErr)
+			assert.Equal(t, tc.path, pathErr.Path)
+		})
+	}
+}
+
+func TestNewStructFilterE_ReportsAllLiteralErrors(t *testing.T) {
+	structFilterJson := `[
+		{"movieYear": {"eq": "nineteen"}, "title": {"matches": "Back to the ("}},
+		{"or": [{"releaseDate": {"after": "someday"}, "kind": {"eq": "MOVIE"}}]}
+	]`
+
+	_, err := NewStructFilterE[Movie](structFilterJson)
+
+	var errs errorx.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3)
+	var paths []string
+	for _, err := range errs {
+		var pathErr *PathError
+		require.ErrorAs(t, err, &pathErr)
+		paths = append(paths, pathErr.Path)
+	}
+	assert.Equal(t, []string{"$[0].movieYear.eq", "$[0].title.matches", "$[1].or[0].releaseDate.after"}, paths)
+	assert.ErrorIs(t, err, ErrTypeMismatch)
+	assert.ErrorIs(t, err, ErrInvalidPattern)
+}
+
+func TestNewStructFilterE_UnrestrictedFieldTypes(t *testing.T) {
+	type Event struct {
+		Name    string `json:"name"`
+		Payload any    `json:"payload"`
+	}
+
+	_, err := NewStructFilterE[Event](`[{"payload": {"eq": 42}}, {"payload": {"contains": "x"}}]`)
+
+	assert.NoError(t, err)
+}
+
+func TestFilterAllFor_LargeIntegers(t *testing.T) {
+	type Account struct {
+		ID      int64  `json:"id"`
+		Balance uint64 `json:"balance"`
+	}
+	accounts := []Account{
+		{ID: 1 << 53, Balance: 1 << 63},
+		{ID: 1<<53 + 1, Balance: 1<<63 + 1},
+		{ID: -(1<<53 + 1), Balance: 0},
+	}
+
+	cases := []struct {
+		name        string
+		filterJson  string
+		expectedIDs []int64
+	}{
+		{"eq", `{"id": {"eq": 9007199254740993}}`, []int64{1<<53 + 1}},
+		{"ne", `{"id": {"ne": 9007199254740992}}`, []int64{1<<53 + 1, -(1<<53 + 1)}},
+		{"in", `{"id": {"in": [9007199254740992, 1]}}`, []int64{1 << 53}},
+		{"gt", `{"id": {"gt": 9007199254740992}}`, []int64{1<<53 + 1}},
+		{"between", `{"id": {"between": [9007199254740993, 9007199254740994]}}`, []int64{1<<53 + 1}},
+		{"negative", `{"id": {"lt": -9007199254740992}}`, []int64{-(1<<53 + 1)}},
+		{"fraction", `{"id": {"gt": 9007199254740992.5}}`, []int64{1<<53 + 1}},
+		{"uint64", `{"balance": {"eq": 9223372036854775809}}`, []int64{1<<53 + 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter := NewStructFilter[Account](tc.filterJson)
+
+			var ids []int64
+			for _, account := range FilterAllFor(structFilter, accounts) {
+				ids = append(ids, account.ID)
+			}
+			assert.Equal(t, tc.expectedIDs, ids)
+		})
+	}
+}
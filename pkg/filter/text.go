@@ -0,0 +1,719 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// textOperators maps the symbols of the textual syntax of
+// filters to the operators of filter.Operator that they are
+// short for; every other operator is written as its name.
+var textOperators = map[string]string{
+	"=":  "eq",
+	"!=": "ne",
+	"<":  "lt",
+	"<=": "lte",
+	">":  "gt",
+	">=": "gte",
+	"~":  "matches",
+	"~*": "imatches",
+}
+
+// ParseText parses the textual syntax of a filter, which is
+// more convenient in URLs and on the command line than JSON,
+// and returns the equivalent JSON representation of the filter,
+// which can be passed to NewStructFilter. For example, this text:
+//
+//	`title ~ "Back to.*" and movieYear >= 1985 and not kind = "SERIES"`
+//
+// is equivalent to:
+//
+//	`[{"title": {"matches": "Back to.*"}}, {"movieYear": {"gte": 1985}}, {"not": {"kind": {"eq": "SERIES"}}}]`
+//
+// Each comparison is a field (or a dotted path), an operator, and a
+// JSON literal (a string, number, boolean or list). The operators are
+// the names of the operators of filter.Operator (e.g. "startsWith" or
+// "in"), or one of the symbols =, !=, <, <=, >, >=, ~ (matches) and ~*
+// (imatches); "exists" and "isNull" don't need a literal. A comparison
+// can be followed by the "normalize" option (e.g. `normalize "unaccent"`).
+//
+// A field whose name is not an identifier, or is a keyword, is written
+// as a JSON path of quoted names (and list indexes), for example:
+//
+//	`$["first-count"] > 2 and $["not"][0] = true`
+//
+// Comparisons are combined with "not", "and" and "or", in that order
+// of precedence, and can be grouped with parentheses. A quantifier
+// of a list field is followed by a comparison of its elements, or by
+// a group of comparisons of the fields of its elements; for example:
+//
+//	`genres any = "Drama" and cast size > 2`
+//	`cast any (name = "Christopher Lloyd" and birthYear < 1940)`
+//
+// An empty text is an empty filter. The error, if any, wraps
+// ErrInvalidFilter, and includes the position of the problem.
+func ParseText(text string) (string, error) {
+	tokens, err := lexText(text)
+	if err != nil {
+		return "", err
+	}
+	p := textParser{tokens: tokens}
+	var expr Expr = And{}
+	if !p.done() {
+		expr, err = p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if !p.done() {
+			return "", p.errorf("unexpected '%s'", p.peek().text)
+		}
+	}
+	filterJson, err := json.Marshal(exprList(expr))
+	if err != nil {
+		return "", err
+	}
+
+	return string(filterJson), nil
+}
+
+// MustParseText is a convenience function that wraps
+// ParseText, but panics if an error occurs.
+func MustParseText(text string) string {
+	filterJson, err := ParseText(text)
+	if err != nil {
+		panic(err)
+	}
+	return filterJson
+}
+
+// FormatText formats the JSON representation of a filter in the
+// textual syntax of ParseText; the result parses into an equivalent
+// filter. The errors are the same as the errors of ParseExpr.
+func FormatText(filterJson string) (string, error) {
+	expr, err := ParseExpr([]byte(filterJson))
+	if err != nil {
+		return "", err
+	}
+	return formatText(expr, ""), nil
+}
+
+type textTokenKind int
+
+const (
+	endToken textTokenKind = iota
+	identToken
+	fieldToken
+	symbolToken
+	literalToken
+)
+
+type textToken struct {
+	kind  textTokenKind
+	text  string
+	value any
+	pos   int
+}
+
+// lexText splits the textual syntax of a filter into tokens.
+func lexText(text string) ([]textToken, error) {
+	var tokens []textToken
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(text[i]):
+			start := i
+			for i < len(text) && (isIdentStart(text[i]) || text[i] == '.' || isDigit(text[i])) {
+				i++
+			}
+			word := text[start:i]
+			switch word {
+			case "true", "false":
+				tokens = append(tokens, textToken{kind: literalToken, text: word, value: word == "true", pos: start})
+			case "null":
+				tokens = append(tokens, textToken{kind: literalToken, text: word, pos: start})
+			default:
+				tokens = append(tokens, textToken{kind: identToken, text: word, pos: start})
+			}
+		case c == '$':
+			start := i
+			path, end, err := lexField(text, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, textToken{kind: fieldToken, text: text[start:i], value: path, pos: start})
+		case c == '"':
+			start := i
+			s, end, err := lexString(text, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			tokens = append(tokens, textToken{kind: literalToken, text: text[start:i], value: s, pos: start})
+		case c == '-' || isDigit(text[i]):
+			start := i
+			for i++; i < len(text) && strings.ContainsRune("0123456789.eE+-", rune(text[i])); i++ {
+			}
+			number := text[start:i]
+			if _, err := strconv.ParseFloat(number, 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid number '%s' at position %d", ErrInvalidFilter, number, start)
+			}
+			tokens = append(tokens, textToken{kind: literalToken, text: number, value: json.Number(number), pos: start})
+		default:
+			start := i
+			symbol := text[i : i+1]
+			if i+1 < len(text) {
+				if _, ok := textOperators[text[i:i+2]]; ok {
+					symbol = text[i : i+2]
+				}
+			}
+			if _, ok := textOperators[symbol]; !ok && !strings.Contains("()[],", symbol) {
+				return nil, fmt.Errorf("%w: unexpected '%s' at position %d", ErrInvalidFilter, symbol, start)
+			}
+			i += len(symbol)
+			tokens = append(tokens, textToken{kind: symbolToken, text: symbol, pos: start})
+		}
+	}
+
+	return tokens, nil
+}
+
+// lexString reads the JSON string that starts at the given
+// position of the text, and returns it along with its end.
+func lexString(text string, start int) (string, int, error) {
+	i := start
+	for i++; i < len(text) && text[i] != '"'; i++ {
+		if text[i] == '\\' {
+			i++
+		}
+	}
+	if i >= len(text) {
+		return "", 0, fmt.Errorf("%w: unterminated string at position %d", ErrInvalidFilter, start)
+	}
+	i++
+	var s string
+	if err := json.Unmarshal([]byte(text[start:i]), &s); err != nil {
+		return "", 0, fmt.Errorf("%w: invalid string at position %d", ErrInvalidFilter, start)
+	}
+
+	return s, i, nil
+}
+
+// lexField reads the quoted field that starts at the given position
+// of the text (e.g. `$["first-count"][0]`), and returns its dotted
+// path (e.g. "first-count.0") along with its end.
+func lexField(text string, start int) (string, int, error) {
+	var names []string
+	i := start + 1
+	for i < len(text) && text[i] == '[' {
+		i++
+		var name string
+		switch {
+		case i < len(text) && text[i] == '"':
+			s, end, err := lexString(text, i)
+			if err != nil {
+				return "", 0, err
+			}
+			name, i = s, end
+		default:
+			digits := i
+			for i < len(text) && isDigit(text[i]) {
+				i++
+			}
+			name = text[digits:i]
+		}
+		if name == "" || strings.Contains(name, ".") || i >= len(text) || text[i] != ']' {
+			return "", 0, fmt.Errorf("%w: invalid field at position %d", ErrInvalidFilter, start)
+		}
+		i++
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return "", 0, fmt.Errorf("%w: invalid field at position %d", ErrInvalidFilter, start)
+	}
+
+	return strings.Join(names, "."), i, nil
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// textParser is a recursive-descent parser of the textual
+// syntax of filters, which parses it into an expression tree.
+type textParser struct {
+	tokens []textToken
+	pos    int
+}
+
+func (p *textParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *textParser) peek() textToken {
+	if p.done() {
+		return textToken{kind: endToken}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *textParser) next() textToken {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+// isKeyword reports whether the next token is the
+// given keyword, in any case.
+func (p *textParser) isKeyword(keyword string) bool {
+	tok := p.peek()
+	return tok.kind == identToken && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *textParser) isSymbol(symbol string) bool {
+	tok := p.peek()
+	return tok.kind == symbolToken && tok.text == symbol
+}
+
+func (p *textParser) expect(symbol string) error {
+	if !p.isSymbol(symbol) {
+		return p.errorf("expected '%s'", symbol)
+	}
+	p.next()
+	return nil
+}
+
+func (p *textParser) errorf(format string, args ...any) error {
+	message := fmt.Sprintf(format, args...)
+	if p.done() {
+		return fmt.Errorf("%w: %s at the end of the filter", ErrInvalidFilter, message)
+	}
+	return fmt.Errorf("%w: %s at position %d", ErrInvalidFilter, message, p.peek().pos)
+}
+
+func (p *textParser) parseOr() (Expr, error) {
+	var or Or
+	for {
+		expr, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, expr)
+		if !p.isKeyword("or") {
+			break
+		}
+		p.next()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (p *textParser) parseAnd() (Expr, error) {
+	var and And
+	for {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		if !p.isKeyword("and") {
+			break
+		}
+		p.next()
+	}
+	return simplify(and), nil
+}
+
+func (p *textParser) parseUnary() (Expr, error) {
+	switch {
+	case p.isKeyword("not"):
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	case p.isSymbol("("):
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	tok := p.peek()
+	if tok.kind != identToken && tok.kind != fieldToken {
+		return nil, p.errorf("expected a field")
+	}
+	p.next()
+	return p.parseComparison(tok.field())
+}
+
+// field returns the path of the field of an identifier
+// or a quoted field (see lexField).
+func (tok textToken) field() string {
+	if tok.kind == fieldToken {
+		return tok.value.(string)
+	}
+	return tok.text
+}
+
+// parseComparison parses the operator and the literal of a
+// comparison of the field with the given path, or a quantifier.
+func (p *textParser) parseComparison(path string) (Expr, error) {
+	tok := p.next()
+	operator := tok.text
+	if tok.kind == symbolToken {
+		operator = textOperators[tok.text]
+	}
+	if _, ok := quantifiers[operator]; ok && tok.kind == identToken {
+		return p.parseQuantifier(path, operator)
+	}
+	if _, ok := operators[operator]; !ok || (tok.kind != identToken && tok.kind != symbolToken) {
+		p.pos--
+		return nil, p.errorf("expected an operator")
+	}
+	var value any = true
+	if (operator != "exists" && operator != "isNull") || p.peek().kind == literalToken {
+		var err error
+		value, err = p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+	}
+	comparison := Comparison{Field: path, Operator: operator, Value: value}
+	if p.isKeyword("normalize") {
+		p.next()
+		form, ok := p.next().value.(string)
+		if !ok {
+			p.pos--
+			return nil, p.errorf("expected a normalization form")
+		}
+		if !normalizationForms[form] {
+			p.pos--
+			return nil, p.errorf("unknown normalization form '%s'", form)
+		}
+		comparison.Normalize = form
+	}
+
+	return comparison, nil
+}
+
+// parseQuantifier parses the comparison of the elements of a
+// quantifier, which is either a single comparison, with or without
+// a field of the elements, or a group of comparisons combined with
+// "and" (the only way that they can be combined in an operator object).
+func (p *textParser) parseQuantifier(path, quantifier string) (Expr, error) {
+	grouped := p.isSymbol("(")
+	if grouped {
+		p.next()
+	}
+	var and And
+	for {
+		elemPath := elementVariable
+		if tok := p.peek(); (tok.kind == identToken && !isTextOperator(tok.text)) || tok.kind == fieldToken {
+			p.next()
+			elemPath += "." + tok.field()
+		}
+		expr, err := p.parseComparison(elemPath)
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, expr)
+		if !grouped || !p.isKeyword("and") {
+			break
+		}
+		p.next()
+	}
+	if grouped {
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	return Quantifier{Field: path, Quantifier: quantifier, Expr: simplify(and)}, nil
+}
+
+// isTextOperator reports whether the given identifier is the
+// name of an operator or quantifier, rather than a field.
+func isTextOperator(name string) bool {
+	_, isOperator := operators[name]
+	_, isQuantifier := quantifiers[name]
+	return isOperator || isQuantifier
+}
+
+func (p *textParser) parseLiteral() (any, error) {
+	if p.isSymbol("[") {
+		p.next()
+		values := []any{}
+		for !p.isSymbol("]") {
+			if len(values) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			value, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		p.next()
+		return values, nil
+	}
+	tok := p.peek()
+	if tok.kind != literalToken {
+		return nil, p.errorf("expected a value")
+	}
+	p.next()
+
+	return tok.value, nil
+}
+
+// exprList converts an expression tree into the list of filter
+// objects of its JSON representation; the clauses of an And are
+// the elements of the list, which are AND'ed together.
+func exprList(expr Expr) []any {
+	switch e := expr.(type) {
+	case And:
+		list := []any{}
+		for _, clause := range e {
+			list = append(list, exprObject(clause))
+		}
+		return list
+	}
+
+	return []any{exprObject(expr)}
+}
+
+// exprObject converts an expression tree into a
+// single filter object of its JSON representation.
+func exprObject(expr Expr) map[string]any {
+	switch e := expr.(type) {
+	case Not:
+		return map[string]any{"not": exprObject(e.Expr)}
+	case Comparison, Quantifier:
+		return operatorObject(e)
+	case Or:
+		list := []any{}
+		for _, clause := range e {
+			list = append(list, exprObject(clause))
+		}
+		return map[string]any{"or": list}
+	}
+
+	return map[string]any{"and": exprList(expr)}
+}
+
+// operatorObject converts a comparison or quantifier (or an And
+// of them, within a quantifier) into an object keyed by its field,
+// whose value is its operator object. The field of a comparison
+// within a quantifier is relative to the element (e.g. "elem.name");
+// the element itself has no key, so the result is the operator
+// object itself. The clauses of an And are merged into a single
+// object, except for the ones that would replace an operator of the
+// object; these are kept in an "and" list of separate objects.
+func operatorObject(expr Expr) map[string]any {
+	var field string
+	var operators map[string]any
+	switch e := expr.(type) {
+	case And:
+		object := map[string]any{}
+		var and []any
+		for _, clause := range e {
+			clauseObject := operatorObject(clause)
+			if canMerge(object, clauseObject) {
+				merge(object, clauseObject)
+				continue
+			}
+			and = append(and, clauseObject)
+		}
+		if len(and) > 0 {
+			existing, _ := object["and"].([]any)
+			object["and"] = append(existing, and...)
+		}
+		return object
+	case Comparison:
+		field = e.Field
+		operators = map[string]any{e.Operator: e.Value}
+		if e.Normalize != "" {
+			operators["normalize"] = e.Normalize
+		}
+	case Quantifier:
+		field = e.Field
+		operators = map[string]any{e.Quantifier: operatorObject(e.Expr)}
+	}
+	field = strings.TrimPrefix(strings.TrimPrefix(field, elementVariable), ".")
+	if field == "" {
+		return operators
+	}
+
+	return map[string]any{field: operators}
+}
+
+// canMerge reports whether the object src can be merged into the
+// object dst without replacing any of its operators (or clauses);
+// the operator objects of the same field are merged recursively.
+// Operator objects with the "normalize" option are not merged with
+// others, since the option applies to all of their operators.
+func canMerge(dst, src map[string]any) bool {
+	_, dstNormalized := dst["normalize"]
+	_, srcNormalized := src["normalize"]
+	if len(dst) > 0 && len(src) > 0 && (dstNormalized || srcNormalized) {
+		return false
+	}
+	for key, srcValue := range src {
+		dstValue, ok := dst[key]
+		if !ok {
+			continue
+		}
+		if isReserved(key) {
+			return false
+		}
+		dstObject, ok := dstValue.(map[string]any)
+		if !ok {
+			return false
+		}
+		srcObject, ok := srcValue.(map[string]any)
+		if !ok {
+			return false
+		}
+		if !canMerge(dstObject, srcObject) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// merge merges the object src into the object dst; see canMerge.
+func merge(dst, src map[string]any) {
+	for key, srcValue := range src {
+		if dstObject, ok := dst[key].(map[string]any); ok {
+			merge(dstObject, srcValue.(map[string]any))
+			continue
+		}
+		dst[key] = srcValue
+	}
+}
+
+// formatText formats an expression tree in the textual syntax of
+// ParseText. Within a quantifier, the prefix of the fields of the
+// elements is stripped.
+func formatText(expr Expr, prefix string) string {
+	switch e := expr.(type) {
+	case And:
+		parts := make([]string, len(e))
+		for i, clause := range e {
+			parts[i] = formatText(clause, prefix)
+			if _, ok := clause.(Or); ok {
+				parts[i] = "(" + parts[i] + ")"
+			}
+		}
+		return strings.Join(parts, " and ")
+	case Or:
+		parts := make([]string, len(e))
+		for i, clause := range e {
+			parts[i] = formatText(clause, prefix)
+		}
+		return strings.Join(parts, " or ")
+	case Not:
+		if isAtomic(e.Expr) {
+			return "not " + formatText(e.Expr, prefix)
+		}
+		return "not (" + formatText(e.Expr, prefix) + ")"
+	case Comparison:
+		return formatComparison(e, prefix)
+	case Quantifier:
+		field := formatField(strings.TrimPrefix(e.Field, prefix))
+		elemPrefix := elementVariable + "."
+		inner := formatText(e.Expr, elemPrefix)
+		if c, ok := e.Expr.(Comparison); ok && c.Field == elementVariable {
+			return fmt.Sprintf("%s %s %s", field, e.Quantifier, inner)
+		}
+		return fmt.Sprintf("%s %s (%s)", field, e.Quantifier, inner)
+	}
+
+	return ""
+}
+
+func formatComparison(c Comparison, prefix string) string {
+	var parts []string
+	if c.Field != elementVariable || prefix == "" {
+		parts = append(parts, formatField(strings.TrimPrefix(c.Field, prefix)))
+	}
+	operator := c.Operator
+	for _, symbol := range slices.Sorted(maps.Keys(textOperators)) {
+		if textOperators[symbol] == c.Operator {
+			operator = symbol
+		}
+	}
+	parts = append(parts, operator)
+	if (c.Operator != "exists" && c.Operator != "isNull") || c.Value != true {
+		parts = append(parts, formatLiteral(c.Value))
+	}
+	if c.Normalize != "" {
+		parts = append(parts, "normalize", strconv.Quote(c.Normalize))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// formatField formats the path of a field, which is quoted (see
+// lexField) unless it is a dotted path of identifiers that can't
+// be mistaken for a keyword or a literal.
+func formatField(path string) string {
+	quoted := path == "true" || path == "false" || path == "null"
+	for _, keyword := range []string{"and", "or", "not"} {
+		quoted = quoted || strings.EqualFold(path, keyword)
+	}
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		quoted = quoted || name == "" || (i == 0 && !isIdentStart(name[0]))
+		for j := range len(name) {
+			quoted = quoted || (!isIdentStart(name[j]) && !isDigit(name[j]))
+		}
+	}
+	if !quoted {
+		return path
+	}
+	var b strings.Builder
+	b.WriteString("$")
+	for _, name := range names {
+		if name != "" && strings.Trim(name, "0123456789") == "" {
+			b.WriteString("[" + name + "]")
+			continue
+		}
+		b.WriteString("[" + formatLiteral(name) + "]")
+	}
+
+	return b.String()
+}
+
+func formatLiteral(v any) string {
+	if list, ok := v.([]any); ok {
+		elems := make([]string, len(list))
+		for i, elem := range list {
+			elems[i] = formatLiteral(elem)
+		}
+		return "[" + strings.Join(elems, ", ") + "]"
+	}
+	literal, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(literal)
+}
@@ -0,0 +1,197 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorz"
+)
+
+type textTestCase struct {
+	name         string
+	text         string
+	expectedJson string
+}
+
+func TestParseText(t *testing.T) {
+	cases := []textTestCase{
+		{
+			name:         "and, not",
+			text:         `title ~ "Back to.*" and movieYear >= 1985 and not kind = "SERIES"`,
+			expectedJson: `[{"title": {"matches": "Back to.*"}}, {"movieYear": {"gte": 1985}}, {"not": {"kind": {"eq": "SERIES"}}}]`,
+		},
+		{
+			name:         "or binds more loosely than and",
+			text:         `kind = "MOVIE" and movieYear < 1990 OR title startsWith "The"`,
+			expectedJson: `[{"or": [{"and": [{"kind": {"eq": "MOVIE"}}, {"movieYear": {"lt": 1990}}]}, {"title": {"startsWith": "The"}}]}]`,
+		},
+		{
+			name:         "grouping",
+			text:         `kind = "MOVIE" and (movieYear < 1990 or tagline exists)`,
+			expectedJson: `[{"kind": {"eq": "MOVIE"}}, {"or": [{"movieYear": {"lt": 1990}}, {"tagline": {"exists": true}}]}]`,
+		},
+		{
+			name:         "negated group",
+			text:         `not (kind = "SERIES" or tagline isNull false)`,
+			expectedJson: `[{"not": {"or": [{"kind": {"eq": "SERIES"}}, {"tagline": {"isNull": false}}]}}]`,
+		},
+		{
+			name:         "lists, escapes and normalize",
+			text:         `movieYear between [1980, 1989.5] and title ieq "amélie \"1\"" normalize "unaccent" and kind nin ["SERIES", null]`,
+			expectedJson: `[{"movieYear": {"between": [1980, 1989.5]}}, {"title": {"ieq": "amélie \"1\"", "normalize": "unaccent"}}, {"kind": {"nin": ["SERIES", null]}}]`,
+		},
+		{
+			name:         "dotted path",
+			text:         `director.name != "Frank Darabont"`,
+			expectedJson: `[{"director.name": {"ne": "Frank Darabont"}}]`,
+		},
+		{
+			name:         "quantifiers",
+			text:         `genres any = "Drama" and cast size > 2 and cast none (name ~* "^fox" and birthYear < 1940)`,
+			expectedJson: `[{"genres": {"any": {"eq": "Drama"}}}, {"cast": {"size": {"gt": 2}}}, {"cast": {"none": {"name": {"imatches": "^fox"}, "birthYear": {"lt": 1940}}}}]`,
+		},
+		{
+			name:         "same operator twice in a quantifier",
+			text:         `cast any (name != "Tim Robbins" and birthYear < 1940 and name != "Morgan Freeman")`,
+			expectedJson: `[{"cast": {"any": {"name": {"ne": "Tim Robbins"}, "birthYear": {"lt": 1940}, "and": [{"name": {"ne": "Morgan Freeman"}}]}}}]`,
+		},
+		{
+			name:         "same field with and without normalize in a quantifier",
+			text:         `genres all (ne "Drama" and ieq "comedy" normalize "NFC")`,
+			expectedJson: `[{"genres": {"all": {"ne": "Drama", "and": [{"ieq": "comedy", "normalize": "NFC"}]}}}]`,
+		},
+		{
+			name:         "empty",
+			text:         ` `,
+			expectedJson: `[]`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			filterJson, err := ParseText(tc.text)
+
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expectedJson, filterJson)
+		})
+	}
+}
+
+func TestParseText_Errors(t *testing.T) {
+	cases := []struct {
+		name    string
+		text    string
+		message string
+	}{
+		{name: "missing operator", text: `title "Back"`, message: "expected an operator at position 6"},
+		{name: "unknown operator", text: `title like "Back"`, message: "expected an operator at position 6"},
+		{name: "missing value", text: `title =`, message: "expected a value at the end of the filter"},
+		{name: "missing field", text: `title = "Back" and`, message: "expected a field at the end of the filter"},
+		{name: "unbalanced parentheses", text: `(title = "Back"`, message: "expected ')' at the end of the filter"},
+		{name: "unterminated string", text: `title = "Back`, message: "unterminated string at position 8"},
+		{name: "invalid character", text: `title == "Back" && kind = "MOVIE"`, message: "unexpected '&' at position 16"},
+		{name: "invalid number", text: `movieYear = 19-85`, message: "invalid number '19-85' at position 12"},
+		{name: "or within a quantifier", text: `cast any (name = "x" or birthYear = 1)`, message: "expected ')' at position 21"},
+		{name: "unknown normalization form", text: `title ieq "x" normalize "NFX"`, message: "unknown normalization form 'NFX' at position 24"},
+		{name: "invalid quoted field", text: `kind = "MOVIE" and $[title] = "Back"`, message: "invalid field at position 19"},
+		{name: "quoted field with a dot", text: `$["director.name"] = "x"`, message: "invalid field at position 0"},
+		{name: "trailing tokens", text: `title = "Back" "Future"`, message: "unexpected '\"Future\"' at position 15"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseText(tc.text)
+
+			assert.ErrorIs(t, err, ErrInvalidFilter)
+			assert.ErrorIs(t, err, errorz.ErrBadRequest)
+			assert.ErrorContains(t, err, tc.message)
+		})
+	}
+}
+
+func TestFormatText(t *testing.T) {
+	cases := []textTestCase{
+		{
+			name:         "list",
+			expectedJson: `[{"title": {"matches": "Back to.*"}}, {"movieYear": {"gte": 1985}}, {"not": {"kind": {"eq": "SERIES"}}}]`,
+			text:         `title ~ "Back to.*" and movieYear >= 1985 and not kind = "SERIES"`,
+		},
+		{
+			name:         "or clauses",
+			expectedJson: `{"or": [{"kind": {"eq": "MOVIE"}, "movieYear": {"lt": 1990}}, {"title": {"startsWith": "The"}, "tagline": {"exists": true}}]}`,
+			text:         `kind = "MOVIE" and movieYear < 1990 or title startsWith "The" and tagline exists`,
+		},
+		{
+			name:         "nested groups",
+			expectedJson: `{"kind": {"in": ["MOVIE", "SERIES"]}, "or": [{"title": {"ieq": "x"}}, {"title": {"ieq": "y", "normalize": "NFC"}}], "not": {"director": {"name": {"eq": "a"}, "birthYear": {"gt": 1}}}}`,
+			text:         `kind in ["MOVIE", "SERIES"] and (title ieq "x" or title ieq "y" normalize "NFC") and not (director.name = "a" and director.birthYear > 1)`,
+		},
+		{
+			name:         "quantifiers",
+			expectedJson: `[{"genres": {"any": {"eq": "Drama"}}, "cast": {"size": {"gt": 2}, "all": {"name": {"exists": false}}}}]`,
+			text:         `genres any = "Drama" and cast size > 2 and cast all (name exists false)`,
+		},
+		{
+			name:         "quoted fields",
+			expectedJson: `[{"first-count": {"gt": 2}}, {"Not": {"eq": true}}, {"cast": {"any": {"full name": {"eq": "x"}, "role": {"eq": "y"}}}}, {"crew.0.first-name": {"eq": "Bob"}}]`,
+			text:         `$["first-count"] > 2 and $["Not"] = true and cast any ($["full name"] = "x" and role = "y") and $["crew"][0]["first-name"] = "Bob"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			text, err := FormatText(tc.expectedJson)
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.text, text)
+
+			// The text parses into an equivalent filter,
+			// which is formatted into the same text.
+			filterJson, err := ParseText(text)
+			require.NoError(t, err)
+			text, err = FormatText(filterJson)
+			require.NoError(t, err)
+			assert.Equal(t, tc.text, text)
+		})
+	}
+}
+
+func TestFilterAllFor_Text(t *testing.T) {
+	cases := []operatorTestCase{
+		{
+			name:           "comparisons",
+			filterJson:     `title ~ "^Back" and movieYear >= 1985 and not kind = "SERIES"`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+		{
+			name:           "or",
+			filterJson:     `movieYear > 1990 or director.name = "Robert Zemeckis"`,
+			expectedTitles: []string{"Back to the Future", "The Shawshank Redemption"},
+		},
+		{
+			name:           "quantifier",
+			filterJson:     `cast any (name = "Morgan Freeman" and birthYear < 1940)`,
+			expectedTitles: []string{"The Shawshank Redemption"},
+		},
+		{
+			name:           "same operator twice in a quantifier",
+			filterJson:     `cast any (name != "Tim Robbins" and name != "Morgan Freeman")`,
+			expectedTitles: []string{"Back to the Future"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			structFilter := NewStructFilter[Movie](MustParseText(tc.filterJson))
+
+			result := FilterAllFor(structFilter, testMovieList)
+
+			var titles []string
+			for _, movie := range result {
+				titles = append(titles, movie.Title)
+			}
+			assert.Equal(t, tc.expectedTitles, titles)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package filter
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tartale/go/pkg/jsontime"
+)
+
+var (
+	typeOfTime     = reflect.TypeFor[time.Time]()
+	typeOfJSONTime = reflect.TypeFor[jsontime.Time]()
+)
+
+// timeLayouts are the layouts that date literals are parsed
+// with, after the format of the field (if any).
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	time.DateTime,
+	time.DateOnly,
+}
+
+// isTime reports whether values of the given type (or
+// the type it points to) are compared as dates.
+func isTime(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t == typeOfTime || t == typeOfJSONTime
+}
+
+// parseTime parses a date literal; the literal is either parsed with
+// the given format (the format tag of the field, as used by jsontime),
+// or one of timeLayouts, or it is relative to the current time, in the
+// form "now", "now-7d" or "now+2h30m" (see parseDuration). The result
+// reports whether the literal is relative.
+func parseTime(s, format string) (time.Time, bool, error) {
+	if rest, ok := strings.CutPrefix(s, "now"); ok {
+		t, err := relativeTime(rest)
+		return t, true, err
+	}
+	layouts := timeLayouts
+	if format != "" {
+		layouts = append([]string{format}, layouts...)
+	}
+	for _, layout := range layouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, false, nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("'%s' is not a valid date", s)
+}
+
+// relativeTime returns the current time, offset by the given
+// signed duration (e.g. "-7d"); an empty offset is the current time.
+func relativeTime(offset string) (time.Time, error) {
+	now := time.Now()
+	if offset == "" {
+		return now, nil
+	}
+	sign := offset[0]
+	if sign != '+' && sign != '-' {
+		return time.Time{}, fmt.Errorf("'now%s' is not a valid relative date", offset)
+	}
+	d, err := parseDuration(offset[1:])
+	if err != nil {
+		return time.Time{}, err
+	}
+	if sign == '-' {
+		d = -d
+	}
+
+	return now.Add(d), nil
+}
+
+// parseDuration parses a duration in the format of time.ParseDuration
+// (e.g. "72h"), or a number of days or weeks (e.g. "7d" or "2w").
+func parseDuration(s string) (time.Duration, error) {
+	unit := time.Duration(0)
+	switch {
+	case strings.HasSuffix(s, "d"):
+		unit = 24 * time.Hour
+	case strings.HasSuffix(s, "w"):
+		unit = 7 * 24 * time.Hour
+	}
+	if unit == 0 {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not a valid duration", s)
+		}
+		return d, nil
+	}
+	n, err := strconv.ParseFloat(s[:len(s)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a valid duration", s)
+	}
+
+	return time.Duration(n * float64(unit)), nil
+}
+
+// toTime converts a value into a date; strings are parsed
+// with timeLayouts, or as a relative date.
+func toTime(v any) (time.Time, bool) {
+	switch val := v.(type) {
+	case time.Time:
+		return val, true
+	case string:
+		t, _, err := parseTime(val, "")
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+// toTimes converts two values into dates, if either of them is
+// a date; the result reports whether they are compared as dates.
+func toTimes(a, b any) (time.Time, time.Time, bool, error) {
+	_, aIsTime := a.(time.Time)
+	_, bIsTime := b.(time.Time)
+	if !aIsTime && !bIsTime {
+		return time.Time{}, time.Time{}, false, nil
+	}
+	at, ok := toTime(a)
+	if !ok {
+		return time.Time{}, time.Time{}, true, fmt.Errorf("can't compare %v with a date", a)
+	}
+	bt, ok := toTime(b)
+	if !ok {
+		return time.Time{}, time.Time{}, true, fmt.Errorf("can't compare %v with a date", b)
+	}
+
+	return at, bt, true, nil
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/tartale/go/pkg/errorz"
-	"github.com/tartale/go/pkg/generics"
 	"github.com/vektah/gqlparser/v2/ast"
 )
 
@@ -34,6 +33,22 @@ func (a ArgKey) String() string {
 	return fmt.Sprintf("%s.%s", a.Path, a.Name)
 }
 
+// ArgumentError records an error in the argument of a query field,
+// along with the key of the argument, which ErrorPresenter adds to
+// the extensions of the GraphQL error.
+type ArgumentError struct {
+	Key ArgKey
+	Err error
+}
+
+func (e *ArgumentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ArgumentError) Unwrap() error {
+	return e.Err
+}
+
 // MustGetArgValue is a convenience function that wraps GetArgValue,
 // but panics if an error occurs.
 func MustGetArgValue[T any](ctx context.Context, key ArgKey) *T {
@@ -47,28 +62,41 @@ func MustGetArgValue[T any](ctx context.Context, key ArgKey) *T {
 
 // GetArgValue searches the gqlgen context for a query argument
 // identified by path and name in the ArgKey, and returns
-// the value casted to the given type T. GetArgValue returns an
-// error if:
+// the value decoded into the given type T. Variables are resolved
+// from the operation context, and the default values declared in
+// the schema are applied, both to the argument itself and to the
+// fields of input objects (see WithSchema). Input objects and
+// lists are decoded as JSON, so the json tags of T are respected,
+// as are jsontime.Time fields and their format tags. GetArgValue
+// returns an error if:
 //   - The context is not a gqlgen-provided context
 //   - The field specified by the path is not found in the query
-//   - The argument is not provided in the identified query field
-//   - The value of the argument cannot be cast to the desired type.
+//   - The argument is not provided in the identified query field,
+//     and has no default value
+//   - The value of the argument cannot be decoded into the desired type.
+//
+// The errors about the argument are *ArgumentErrors.
 func GetArgValue[T any](ctx context.Context, key ArgKey) (*T, error) {
 
-	argumentList, err := GetArgList(ctx, key.Path)
+	fctx := graphql.GetFieldContext(ctx)
+	field := FindField(ctx, key.Path, fctx)
+	if field == nil {
+		return nil, fmt.Errorf("%w '%s'", ErrFieldNotFound, key.Path)
+	}
+	val, ok, err := argValue(ctx, field.Field, key.Name)
 	if err != nil {
-		return nil, err
+		return nil, &ArgumentError{Key: key, Err: fmt.Errorf("%w '%s': %w", errorz.ErrInvalidArgument, key, err)}
 	}
-	arg := argumentList.ForName(key.Name)
-	if arg == nil {
-		return nil, fmt.Errorf("%w '%s'", ErrArgumentNotFound, key)
+	if !ok {
+		return nil, &ArgumentError{Key: key, Err: fmt.Errorf("%w '%s'", ErrArgumentNotFound, key)}
 	}
-	val, err := arg.Value.Value(nil)
+
+	result, err := decodeValue[T](val)
 	if err != nil {
-		return nil, err
+		return nil, &ArgumentError{Key: key, Err: fmt.Errorf("%w '%s': %w", errorz.ErrInvalidArgument, key, err)}
 	}
 
-	return generics.CastTo[T](val)
+	return result, nil
 }
 
 // GetArgList searches the gqlgen context for a query field
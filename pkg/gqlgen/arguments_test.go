@@ -1,23 +1,159 @@
 package gqlgen
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorz"
+	"github.com/tartale/go/pkg/jsontime"
 )
 
-// TODO - figure out how to construct a gqlgen context
+type testDateRange struct {
+	From jsontime.Time `json:"from" format:"2006-01-02"`
+	To   jsontime.Time `json:"to" format:"2006-01-02"`
+}
+
+type testMovieFilter struct {
+	Title    string         `json:"title"`
+	Kinds    []string       `json:"kinds"`
+	Released *testDateRange `json:"released"`
+}
+
 func TestGetArgValue(t *testing.T) {
+	ctx := newTestContext(t, `{ movies(filter: {title: "Back to the Future", released: {from: "1985-07-03"}}, limit: 5) { title } }`, nil)
+
+	filter, err := GetArgValue[testMovieFilter](ctx, ArgKey{Path: "movies", Name: "filter"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Back to the Future", filter.Title)
+	assert.Equal(t, []string{"MOVIE"}, filter.Kinds)
+	assert.Equal(t, time.Date(1985, 7, 3, 0, 0, 0, 0, time.UTC), filter.Released.From.Time)
+	assert.Equal(t, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), filter.Released.To.Time)
+	limit, err := GetArgValue[int](ctx, ArgKey{Path: "movies", Name: "limit"})
+	require.NoError(t, err)
+	assert.Equal(t, 5, *limit)
+}
+
+func TestGetArgValue_Variables(t *testing.T) {
+	query := `query($filter: MovieFilter, $titles: [String!] = ["Big"]) { movies(filter: $filter, titles: $titles) { title } }`
+	ctx := newTestContext(t, query, map[string]any{
+		"filter": map[string]any{"kinds": []any{"SERIES"}, "released": map[string]any{"from": "1990-01-01", "to": "1999-12-31"}},
+	})
+
+	filter, err := GetArgValue[testMovieFilter](ctx, ArgKey{Path: "movies", Name: "filter"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SERIES"}, filter.Kinds)
+	assert.Equal(t, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), filter.Released.To.Time)
+
+	titles, err := GetArgValue[[]string](ctx, ArgKey{Path: "movies", Name: "titles"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Big"}, *titles)
+}
+
+func TestGetArgValue_Defaults(t *testing.T) {
+	ctx := newTestContext(t, `query($limit: Int) { movies(limit: $limit) { title cast(first: 3) { movies { title } } } }`, nil)
+
+	limit, err := GetArgValue[int](ctx, ArgKey{Path: "movies", Name: "limit"})
+	require.NoError(t, err)
+	assert.Equal(t, 25, *limit)
+
+	limit, err = GetArgValue[int](ctx, ArgKey{Path: "movies.cast.movies", Name: "limit"})
+	require.NoError(t, err)
+	assert.Equal(t, 10, *limit)
+
+	first, err := GetArgValue[int64](ctx, ArgKey{Path: "movies.cast", Name: "first"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), *first)
+}
+
+func TestGetArgValue_Schema(t *testing.T) {
+	query := `query($filter: MovieFilter) { movies(filter: $filter) { title } }`
+	ctx := newTestContext(t, query, map[string]any{
+		"filter": map[string]any{"released": map[string]any{"from": "1990-01-01"}},
+	})
+
+	filter, err := GetArgValue[testMovieFilter](WithSchema(ctx, testExecutableSchema.Schema()), ArgKey{Path: "movies", Name: "filter"})
 
-	assert.True(t, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"MOVIE"}, filter.Kinds)
+	assert.Equal(t, time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), filter.Released.To.Time)
+}
+
+func TestGetArgValue_Errors(t *testing.T) {
+	ctx := newTestContext(t, `{ movies(filter: {title: "Big"}) { title } }`, nil)
+
+	_, err := GetArgValue[[]string](ctx, ArgKey{Path: "movies", Name: "titles"})
+	assert.ErrorIs(t, err, ErrArgumentNotFound)
+	_, err = GetArgValue[int](ctx, ArgKey{Path: "movies.director", Name: "limit"})
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+	_, err = GetArgValue[int](ctx, ArgKey{Path: "movies", Name: "filter"})
+	assert.ErrorIs(t, err, errorz.ErrInvalidArgument)
+}
+
+func TestGetArgValue_Dates(t *testing.T) {
+	ctx := newTestContext(t, `{ movies(filter: {released: {to: "1999-12-31"}}) { title } }`, nil)
+
+	filter, err := GetArgValue[testMovieFilter](ctx, ArgKey{Path: "movies", Name: "filter"})
+
+	require.NoError(t, err)
+	assert.True(t, filter.Released.From.IsZero())
+	assert.Equal(t, time.Date(1999, 12, 31, 0, 0, 0, 0, time.UTC), filter.Released.To.Time)
+}
+
+func TestGetArgValue_DatePointers(t *testing.T) {
+	type usDateRange struct {
+		From *jsontime.Time `json:"from" format:"01/02/2006"`
+		To   *jsontime.Time `json:"to" format:"01/02/2006"`
+	}
+	type usMovieFilter struct {
+		Released *usDateRange `json:"released"`
+	}
+	ctx := newTestContext(t, `{ movies(filter: {released: {from: "01/15/2020", to: "12/31/2020"}}) { title } }`, nil)
+
+	filter, err := GetArgValue[usMovieFilter](ctx, ArgKey{Path: "movies", Name: "filter"})
+
+	require.NoError(t, err)
+	require.NotNil(t, filter.Released.From)
+	assert.Equal(t, time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC), filter.Released.From.Time)
+	assert.Equal(t, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), filter.Released.To.Time)
+
+	ctx = newTestContext(t, `{ movies(filter: {released: {from: "2020-01-15", to: "12/31/2020"}}) { title } }`, nil)
+	_, err = GetArgValue[usMovieFilter](ctx, ArgKey{Path: "movies", Name: "filter"})
+	assert.ErrorIs(t, err, errorz.ErrInvalidArgument)
+}
+
+func TestGetArgValue_InvalidDate(t *testing.T) {
+	ctx := newTestContext(t, `{ movies(filter: {released: {from: "garbage"}}) { title } }`, nil)
+
+	_, err := GetArgValue[testMovieFilter](ctx, ArgKey{Path: "movies", Name: "filter"})
+
+	assert.ErrorIs(t, err, errorz.ErrInvalidArgument)
+	var argErr *ArgumentError
+	require.ErrorAs(t, err, &argErr)
+	assert.Equal(t, ArgKey{Path: "movies", Name: "filter"}, argErr.Key)
+	assert.ErrorContains(t, err, "'garbage'")
 }
 
 func TestGetArgList(t *testing.T) {
+	ctx := newTestContext(t, `{ person(name: "Doc Brown") { movies(limit: 3) { title } } }`, nil)
+
+	args, err := GetArgList(ctx, "person.movies")
 
-	assert.True(t, true)
+	require.NoError(t, err)
+	require.Len(t, args, 1)
+	assert.Equal(t, "limit", args[0].Name)
 }
 
 func TestFindField(t *testing.T) {
+	ctx := newTestContext(t, `{ person(name: "Doc Brown") { name movies { title cast { name } } } }`, nil)
 
-	assert.True(t, true)
+	field := FindField(ctx, "person.movies.cast", graphql.GetFieldContext(ctx))
+	require.NotNil(t, field)
+	assert.Equal(t, "cast", field.Name)
+	assert.Nil(t, FindField(ctx, "person.cast", graphql.GetFieldContext(ctx)))
+	assert.Nil(t, FindField(context.Background(), "person", nil))
 }
@@ -0,0 +1,238 @@
+package gqlgen
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/tartale/go/pkg/errorz"
+	"github.com/tartale/go/pkg/generics"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// ErrQueryTooComplex is returned by CostLimit when an operation
+// exceeds one of its limits.
+var ErrQueryTooComplex = fmt.Errorf("%w: the query is too complex", errorz.ErrBadRequest)
+
+// CostDirective is the name of the directive that sets the weight
+// of a field in the schema (see CostAnalyzer); it is declared as:
+//
+//	directive @cost(weight: Int!) on FIELD_DEFINITION
+const CostDirective = "cost"
+
+// DefaultCostMultipliers are the names of the arguments of list fields
+// that CostAnalyzer multiplies the cost of their selections by, unless
+// the analyzer has its own Multipliers.
+var DefaultCostMultipliers = []string{"first", "last", "limit"}
+
+// Cost is the result of the analysis of an operation by a CostAnalyzer.
+type Cost struct {
+	// Depth is the maximum nesting depth of the selected
+	// fields; the root fields have a depth of 1.
+	Depth int
+	// Nodes is the number of selected fields.
+	Nodes int
+	// Weight is the sum of the weights of the selected fields, where
+	// the weight of the selections of a list field is multiplied by
+	// the size of the list that is requested (see Multipliers).
+	Weight int
+}
+
+// CostAnalyzer computes the Cost of GraphQL operations. The weight of a
+// field is, by order of precedence, its weight in the Weights map, the
+// weight of its @cost directive in the schema, or 1; for example, with
+// the weights {"Movie.cast": 5}, and the schema:
+//
+//	type Query {
+//		movies(limit: Int): [Movie!]! @cost(weight: 2)
+//	}
+//
+// the query `{ movies(limit: 10) { title cast { name } } }` has a depth
+// of 3, 4 nodes, and a weight of 2 + 10 * (1 + 5 + 1) = 72.
+type CostAnalyzer struct {
+	// Weights are the weights of fields, keyed by the name
+	// of their type and the name of the field (e.g. "Movie.cast").
+	Weights map[string]int
+	// Multipliers are the names of the integer arguments of list fields
+	// that multiply the weight of their selections; the first argument
+	// that has a positive value is used. If nil, DefaultCostMultipliers
+	// are used.
+	Multipliers []string
+}
+
+// Analyze computes the cost of the operation of the given operation
+// context. Fragments and inline fragments are resolved, and the fields
+// that are skipped with the @skip and @include directives are left out;
+// the selections of fragments on different types (e.g. of a union) are
+// all counted, so the cost is an upper bound.
+func (a CostAnalyzer) Analyze(octx *graphql.OperationContext) Cost {
+
+	if octx == nil || octx.Operation == nil {
+		return Cost{}
+	}
+
+	return a.analyze(octx, octx.Operation.SelectionSet, 1)
+}
+
+func (a CostAnalyzer) analyze(octx *graphql.OperationContext, selections ast.SelectionSet, depth int) Cost {
+
+	var cost Cost
+	for _, cf := range graphql.CollectFields(octx, selections, nil) {
+		child := a.analyze(octx, cf.Selections, depth+1)
+		cost.Depth = max(cost.Depth, depth, child.Depth)
+		cost.Nodes += 1 + child.Nodes
+		weight := saturatedAdd(a.weight(cf), saturatedMul(a.multiplier(octx, cf), child.Weight))
+		cost.Weight = saturatedAdd(cost.Weight, weight)
+	}
+
+	return cost
+}
+
+// saturatedAdd and saturatedMul add and multiply weights, but
+// saturate at math.MaxInt rather than overflowing, so that the
+// multipliers of nested lists can't wrap the cost of an operation
+// around to a small (or negative) weight.
+func saturatedAdd(a, b int) int {
+	if a > 0 && b > math.MaxInt-a {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatedMul(a, b int) int {
+	if a > 0 && b > 0 && a > math.MaxInt/b {
+		return math.MaxInt
+	}
+	return a * b
+}
+
+// weight returns the weight of the given field.
+func (a CostAnalyzer) weight(cf graphql.CollectedField) int {
+
+	if cf.ObjectDefinition != nil {
+		if weight, ok := a.Weights[cf.ObjectDefinition.Name+"."+cf.Name]; ok {
+			return weight
+		}
+	}
+	if cf.Definition != nil {
+		if directive := cf.Definition.Directives.ForName(CostDirective); directive != nil {
+			if arg := directive.Arguments.ForName("weight"); arg != nil {
+				val, err := arg.Value.Value(nil)
+				if err == nil {
+					if weight, err := generics.CastTo[int](val); err == nil {
+						return *weight
+					}
+				}
+			}
+		}
+	}
+
+	return 1
+}
+
+// multiplier returns the number of times that the weight of the
+// selections of the given field is counted: the requested size of
+// the list, if the field is a list, or 1.
+func (a CostAnalyzer) multiplier(octx *graphql.OperationContext, cf graphql.CollectedField) int {
+
+	if cf.Definition == nil || cf.Definition.Type.Elem == nil {
+		return 1
+	}
+	multipliers := a.Multipliers
+	if multipliers == nil {
+		multipliers = DefaultCostMultipliers
+	}
+	// The map has the arguments that the field declares, with their defaults.
+	args := cf.ArgumentMap(octx.Variables)
+	for _, name := range multipliers {
+		if args[name] == nil {
+			continue
+		}
+		size, err := generics.CastTo[int](args[name])
+		if err == nil && *size > 0 {
+			return *size
+		}
+	}
+
+	return 1
+}
+
+const costExtension = "CostLimit"
+
+// CostLimit is a gqlgen handler extension that rejects the operations
+// that are too deep, select too many fields, or are too expensive, as
+// computed by its CostAnalyzer, before they are executed; a limit of
+// zero means that there is no limit:
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.Use(&gqlgen.CostLimit{
+//		CostAnalyzer: gqlgen.CostAnalyzer{Weights: map[string]int{"Movie.cast": 5}},
+//		MaxDepth:     10,
+//		MaxCost:      1000,
+//	})
+//
+// The error of a rejected operation wraps ErrQueryTooComplex, and its
+// code is one of "DEPTH_LIMIT_EXCEEDED", "NODE_LIMIT_EXCEEDED" or
+// "COST_LIMIT_EXCEEDED". The cost of the operations that are accepted
+// is available to resolvers with GetCost.
+type CostLimit struct {
+	CostAnalyzer
+	MaxDepth int
+	MaxNodes int
+	MaxCost  int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &CostLimit{}
+
+func (c *CostLimit) ExtensionName() string {
+	return costExtension
+}
+
+func (c *CostLimit) Validate(schema graphql.ExecutableSchema) error {
+	if c.MaxDepth < 0 || c.MaxNodes < 0 || c.MaxCost < 0 {
+		return fmt.Errorf("%w: the limits of CostLimit can't be negative", errorz.ErrInvalidArgument)
+	}
+	return nil
+}
+
+func (c *CostLimit) MutateOperationContext(ctx context.Context, octx *graphql.OperationContext) *gqlerror.Error {
+
+	cost := c.Analyze(octx)
+	octx.Stats.SetExtension(costExtension, &cost)
+
+	switch {
+	case c.MaxDepth > 0 && cost.Depth > c.MaxDepth:
+		return costError("DEPTH_LIMIT_EXCEEDED", "operation has a depth of %d, which exceeds the limit of %d", cost.Depth, c.MaxDepth)
+	case c.MaxNodes > 0 && cost.Nodes > c.MaxNodes:
+		return costError("NODE_LIMIT_EXCEEDED", "operation selects %d fields, which exceeds the limit of %d", cost.Nodes, c.MaxNodes)
+	case c.MaxCost > 0 && cost.Weight > c.MaxCost:
+		return costError("COST_LIMIT_EXCEEDED", "operation has a cost of %d, which exceeds the limit of %d", cost.Weight, c.MaxCost)
+	}
+
+	return nil
+}
+
+func costError(code, format string, args ...any) *gqlerror.Error {
+	err := gqlerror.Wrap(fmt.Errorf("%w: "+format, append([]any{ErrQueryTooComplex}, args...)...))
+	errcode.Set(err, code)
+
+	return err
+}
+
+// GetCost returns the cost of the operation of the gqlgen context,
+// as computed by a CostLimit extension, or nil if there isn't one.
+func GetCost(ctx context.Context) *Cost {
+
+	if !graphql.HasOperationContext(ctx) {
+		return nil
+	}
+	cost, _ := graphql.GetOperationContext(ctx).Stats.GetExtension(costExtension).(*Cost)
+
+	return cost
+}
@@ -0,0 +1,96 @@
+package gqlgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCostAnalyzer = CostAnalyzer{Weights: map[string]int{"Movie.cast": 5}}
+
+func TestCostAnalyzer_Analyze(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]any
+		expected  Cost
+	}{
+		{"weights and multiplier", `{ movies(limit: 10) { title cast { name } } }`, nil, Cost{Depth: 3, Nodes: 4, Weight: 72}},
+		{"default multiplier", `{ movies { title } }`, nil, Cost{Depth: 2, Nodes: 2, Weight: 27}},
+		{"variable multiplier", `query($n: Int) { person(name: "Doc Brown") { movies(limit: $n) { title } } }`, map[string]any{"n": 3}, Cost{Depth: 3, Nodes: 3, Weight: 5}},
+		{"aliases", `{ a: person(name: "Marty") { name } b: person(name: "Doc") { name } }`, nil, Cost{Depth: 2, Nodes: 4, Weight: 4}},
+		{
+			"fragments",
+			`query($skip: Boolean!) { person(name: "Doc") { ...personFields ... on Person { movies(limit: 2) @skip(if: $skip) { cast(first: 4) { name } } } } }
+			fragment personFields on Person { name }`,
+			map[string]any{"skip": false},
+			Cost{Depth: 4, Nodes: 5, Weight: 1 + 1 + 1 + 2*(5+4*1)},
+		},
+		{"skipped", `query($skip: Boolean!) { person(name: "Doc") { name movies @skip(if: $skip) { title } } }`, map[string]any{"skip": true}, Cost{Depth: 2, Nodes: 2, Weight: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(t, tt.query, tt.variables)
+
+			cost := testCostAnalyzer.Analyze(graphql.GetOperationContext(ctx))
+
+			assert.Equal(t, tt.expected, cost)
+		})
+	}
+	assert.Equal(t, Cost{}, CostAnalyzer{}.Analyze(nil))
+}
+
+func TestCostLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit CostLimit
+		code  string
+	}{
+		{"depth", CostLimit{MaxDepth: 2}, "DEPTH_LIMIT_EXCEEDED"},
+		{"nodes", CostLimit{MaxNodes: 3}, "NODE_LIMIT_EXCEEDED"},
+		{"cost", CostLimit{MaxCost: 50}, "COST_LIMIT_EXCEEDED"},
+		{"accepted", CostLimit{MaxDepth: 3, MaxNodes: 4, MaxCost: 100}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := tt.limit
+			limit.CostAnalyzer = testCostAnalyzer
+			exec := executor.New(testExecutableSchema)
+			exec.Use(&limit)
+			ctx := graphql.StartOperationTrace(context.Background())
+
+			octx, errs := exec.CreateOperationContext(ctx, &graphql.RawParams{Query: `{ movies(limit: 10) { title cast { name } } }`})
+
+			if tt.code == "" {
+				require.Empty(t, errs)
+				cost := GetCost(graphql.WithOperationContext(ctx, octx))
+				require.NotNil(t, cost)
+				assert.Equal(t, 72, cost.Weight)
+				return
+			}
+			require.Len(t, errs, 1)
+			assert.Equal(t, tt.code, errs[0].Extensions["code"])
+			assert.ErrorIs(t, errs[0], ErrQueryTooComplex)
+		})
+	}
+	assert.Nil(t, GetCost(context.Background()))
+}
+
+func TestCostLimit_Overflow(t *testing.T) {
+	limit := CostLimit{CostAnalyzer: testCostAnalyzer, MaxCost: 1000}
+	exec := executor.New(testExecutableSchema)
+	exec.Use(&limit)
+	ctx := graphql.StartOperationTrace(context.Background())
+	query := `{ movies(limit: 2147483647) { cast(first: 2147483647) { movies(limit: 2147483647) {
+		cast(first: 2147483647) { movies(limit: 2147483647) { title } } } } } }`
+
+	_, errs := exec.CreateOperationContext(ctx, &graphql.RawParams{Query: query})
+
+	require.Len(t, errs, 1)
+	assert.Equal(t, "COST_LIMIT_EXCEEDED", errs[0].Extensions["code"])
+	assert.ErrorIs(t, errs[0], ErrQueryTooComplex)
+}
@@ -0,0 +1,128 @@
+package gqlgen
+
+import (
+	"context"
+	"errors"
+	"runtime/debug"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/tartale/go/pkg/errorz"
+	"github.com/tartale/go/pkg/filter"
+	"github.com/tartale/go/pkg/logz"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// CodeInternal is the code of the errors that are
+// not meant to be shown to clients (see Recover).
+const CodeInternal = "INTERNAL_SERVER_ERROR"
+
+// internalMessage is the message of the errors with the code
+// CodeInternal that are shown to clients, instead of their own.
+const internalMessage = "internal system error"
+
+// ErrorCode maps the errors that wrap Err to
+// the Code in the extensions of a GraphQL error.
+type ErrorCode struct {
+	Err  error
+	Code string
+}
+
+// ErrorCodes are the codes that ErrorPresenter gives to errors, by
+// the errorz sentinel they wrap; the first matching sentinel wins,
+// so the more specific sentinels come first. The codes can be
+// changed, or more added, before any queries are served, such
+// as in an init function.
+var ErrorCodes = []ErrorCode{
+	{Err: errorz.ErrInvalidArgument, Code: "BAD_USER_INPUT"},
+	{Err: errorz.ErrBadRequest, Code: "BAD_REQUEST"},
+	{Err: errorz.ErrNotFound, Code: "NOT_FOUND"},
+	{Err: errorz.ErrInvalidType, Code: CodeInternal},
+	{Err: errorz.ErrResponse, Code: CodeInternal},
+	{Err: errorz.ErrFatal, Code: CodeInternal},
+}
+
+// GetErrorCode returns the code of the given
+// error in ErrorCodes, or "" if there isn't one.
+func GetErrorCode(err error) string {
+	for _, errorCode := range ErrorCodes {
+		if errors.Is(err, errorCode.Err) {
+			return errorCode.Code
+		}
+	}
+
+	return ""
+}
+
+// ErrorPresenter is a gqlgen error presenter that sets the code in the
+// extensions of a GraphQL error, by the errorz sentinel that the error
+// wraps (see ErrorCodes), unless the error already has a code. For an
+// *ArgumentError, it also adds the key of the argument ("argument"),
+// and for an error in a filter (a *filter.PathError), the JSON path
+// in the value of the argument ("argumentPath"). The message of an
+// error with the code CodeInternal is logged, and replaced with a
+// generic "internal system error", as with Recover. For example:
+//
+//	{
+//		"message": "invalid argument 'movies.filter': bad request: invalid filter: invalid pattern: ... at '$.title.matches'",
+//		"path": ["movies"],
+//		"extensions": {"code": "BAD_USER_INPUT", "argument": "movies.filter", "argumentPath": "$.title.matches"}
+//	}
+//
+// It is set on the gqlgen server:
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.SetErrorPresenter(gqlgen.ErrorPresenter)
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = map[string]any{}
+	}
+	if _, ok := gqlErr.Extensions["code"]; !ok {
+		if code := GetErrorCode(err); code != "" {
+			gqlErr.Extensions["code"] = code
+		}
+	}
+	if gqlErr.Extensions["code"] == CodeInternal && gqlErr.Message != internalMessage {
+		logz.Logger().Infof("internal error: %s\n", gqlErr.Message)
+		gqlErr.Message = internalMessage
+	}
+	var argErr *ArgumentError
+	if errors.As(err, &argErr) {
+		gqlErr.Extensions["argument"] = argErr.Key.String()
+	}
+	var pathErr *filter.PathError
+	if errors.As(err, &pathErr) {
+		gqlErr.Extensions["argumentPath"] = pathErr.Path
+	}
+	if len(gqlErr.Extensions) == 0 {
+		gqlErr.Extensions = nil
+	}
+
+	return gqlErr
+}
+
+// Recover is a gqlgen recover func that turns the panics of resolvers
+// into errors. The panics of the Must* functions (e.g. MustGetArgValue)
+// with an error that has a code for clients in ErrorCodes are returned
+// as that error (so ErrorPresenter shows them as if the resolver had
+// returned them); any other panic is logged with its stack trace, and
+// is returned as a generic "internal system error" with the code
+// CodeInternal, which doesn't reveal its details to clients.
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.SetRecoverFunc(gqlgen.Recover)
+func Recover(ctx context.Context, p any) error {
+
+	if err, ok := p.(error); ok {
+		if code := GetErrorCode(err); code != "" && code != CodeInternal {
+			return err
+		}
+	}
+	logz.Logger().Infof("recovered from panic: %v\n%s\n", p, debug.Stack())
+	err := gqlerror.Errorf(internalMessage)
+	errcode.Set(err, CodeInternal)
+
+	return err
+}
@@ -0,0 +1,82 @@
+package gqlgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorz"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestErrorPresenter(t *testing.T) {
+	ctx := newTestContext(t, `{ films(filter: {title: {matches: "(Back"}}) { title } }`, nil)
+	_, filterErr := Filterable(ctx, nil, resolveFilms)
+	_, argErr := GetArgValue[int](ctx, ArgKey{Path: "films", Name: "offset"})
+	coded := gqlerror.ErrorPathf(ast.Path{ast.PathName("films")}, "too deep")
+	errcode.Set(coded, "DEPTH_LIMIT_EXCEEDED")
+
+	tests := []struct {
+		name       string
+		err        error
+		extensions map[string]any
+	}{
+		{"filter", filterErr, map[string]any{"code": "BAD_USER_INPUT", "argument": "films.filter", "argumentPath": "$.title.matches"}},
+		{"argument", argErr, map[string]any{"code": "NOT_FOUND", "argument": "films.offset"}},
+		{"sentinel", fmt.Errorf("%w: movie 42", errorz.ErrNotFound), map[string]any{"code": "NOT_FOUND"}},
+		{"internal", fmt.Errorf("%w: database is down", errorz.ErrFatal), map[string]any{"code": CodeInternal}},
+		{"existing code", coded, map[string]any{"code": "DEPTH_LIMIT_EXCEEDED"}},
+		{"no code", errors.New("something happened"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.err)
+
+			gqlErr := ErrorPresenter(ctx, tt.err)
+
+			if tt.extensions["code"] == CodeInternal {
+				assert.Equal(t, "internal system error", gqlErr.Message)
+			} else {
+				assert.Contains(t, tt.err.Error(), gqlErr.Message)
+			}
+			assert.Equal(t, tt.extensions, gqlErr.Extensions)
+			assert.Equal(t, ast.Path{ast.PathName("films")}, gqlErr.Path)
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	ctx := newTestContext(t, `{ films { title } }`, nil)
+
+	var recovered any
+	func() {
+		defer func() { recovered = recover() }()
+		MustGetArgValue[int](ctx, ArgKey{Path: "films", Name: "offset"})
+	}()
+	err := Recover(ctx, recovered)
+	assert.ErrorIs(t, err, ErrArgumentNotFound)
+	assert.Equal(t, "NOT_FOUND", ErrorPresenter(ctx, err).Extensions["code"])
+
+	tests := []struct {
+		name  string
+		panic any
+	}{
+		{"runtime error", fmt.Errorf("%w: index out of range", errorz.ErrFatal)},
+		{"string", "unexpected state"},
+		{"unknown error", errors.New("connection refused")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Recover(context.Background(), tt.panic)
+
+			gqlErr := ErrorPresenter(ctx, err)
+			assert.Equal(t, "internal system error", gqlErr.Message)
+			assert.Equal(t, CodeInternal, gqlErr.Extensions["code"])
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package gqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tartale/go/pkg/errorz"
+	"github.com/tartale/go/pkg/filter"
+)
+
+// FilterArgs are the names of the arguments of a field that
+// ApplyFilterArgs reads. An argument that is not declared by the
+// field, or is not provided in the query, is ignored.
+type FilterArgs struct {
+	// Filter is the argument with the filter of the field, which has
+	// an input type of filter.GenerateGraphQL.
+	Filter string
+	// OrderBy is the argument with a list of input objects with the
+	// same fields as filter.OrderBy.
+	OrderBy string
+	// Offset and Limit are the integer arguments with the pagination
+	// of the field; a limit of zero means that the number of results
+	// is not limited.
+	Offset string
+	Limit  string
+}
+
+// DefaultFilterArgs are the names of the arguments that are read by
+// ApplyFilterArgs; they can be changed before any queries are served,
+// such as in an init function.
+var DefaultFilterArgs = FilterArgs{
+	Filter:  "filter",
+	OrderBy: "orderBy",
+	Offset:  "offset",
+	Limit:   "limit",
+}
+
+// FilterableDirective is the name of the directive
+// that FilterMiddleware looks for in the schema.
+const FilterableDirective = "filterable"
+
+// Filterable implements a @filterable directive, which applies the filter,
+// ordering and pagination arguments of a field to the list that is returned
+// by its resolver (see ApplyFilterArgs). The directive is declared in the
+// schema as:
+//
+//	directive @filterable on FIELD_DEFINITION
+//
+//	type Query {
+//		movies(filter: MovieFilter, orderBy: [OrderBy!], offset: Int, limit: Int): [Movie!]! @filterable
+//	}
+//
+// and its implementation is set in the configuration of the generated
+// executable schema:
+//
+//	cfg := generated.Config{Resolvers: &resolvers.Resolver{}}
+//	cfg.Directives.Filterable = gqlgen.Filterable
+func Filterable(ctx context.Context, obj any, next graphql.Resolver) (any, error) {
+
+	res, err := next(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	return ApplyFilterArgs(ctx, res)
+}
+
+// FilterMiddleware is a gqlgen field middleware that does the same as
+// the Filterable directive, for the fields that have the @filterable
+// directive in the schema; it can be used instead of setting the
+// implementation of the directive (but not in addition to it, since
+// the arguments would be applied twice):
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.AroundFields(gqlgen.FilterMiddleware)
+func FilterMiddleware(ctx context.Context, next graphql.Resolver) (any, error) {
+
+	fctx := graphql.GetFieldContext(ctx)
+	if fctx == nil || fctx.Field.Definition == nil || fctx.Field.Definition.Directives.ForName(FilterableDirective) == nil {
+		return next(ctx)
+	}
+
+	return Filterable(ctx, nil, next)
+}
+
+// ApplyFilterArgs reads the filter, ordering and pagination arguments
+// of the field of the gqlgen context (see DefaultFilterArgs), and
+// applies them to the given result of its resolver, which must be a
+// slice (of structs, maps, or pointers to them); the result is a new
+// slice of the same type. The filter is applied as a filter.MapFilter,
+// so its fields are the JSON names of the fields of the elements; the
+// fields of the filter are validated by gqlgen against its input type
+// and, if the elements are structs, against their schema, as with
+// filter.NewMapFilterForE (e.g. the operators that their filter tags
+// allow), as are the fields of the ordering.
+// ApplyFilterArgs returns an error, rather than panicking, if:
+//   - The context is not a gqlgen-provided context
+//   - The result is not a slice
+//   - An argument is not valid, or the filter can't be evaluated.
+//
+// gqlgen reports the error as an error of the field in the response.
+func ApplyFilterArgs(ctx context.Context, res any) (any, error) {
+
+	fctx := graphql.GetFieldContext(ctx)
+	if fctx == nil || !graphql.HasOperationContext(ctx) {
+		return nil, fmt.Errorf("%w: not a gqlgen context", ErrFieldNotFound)
+	}
+	if res == nil {
+		return nil, nil
+	}
+	vals := reflect.ValueOf(res)
+	if vals.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("%w: the result of a filterable field must be a slice, not %T", errorz.ErrInvalidType, res)
+	}
+	path := fctx.Path().String()
+	args := DefaultFilterArgs
+
+	filterVal, err := optionalArgValue[any](ctx, ArgKey{Path: path, Name: args.Filter})
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := optionalArgValue[[]filter.OrderBy](ctx, ArgKey{Path: path, Name: args.OrderBy})
+	if err != nil {
+		return nil, err
+	}
+	offset, err := optionalArgValue[int](ctx, ArgKey{Path: path, Name: args.Offset})
+	if err != nil {
+		return nil, err
+	}
+	limit, err := optionalArgValue[int](ctx, ArgKey{Path: path, Name: args.Limit})
+	if err != nil {
+		return nil, err
+	}
+	err = validateFilterArgs(path, args, vals.Type().Elem(), orderBy, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]any, vals.Len())
+	for i := range items {
+		items[i] = vals.Index(i).Interface()
+	}
+	if filterVal != nil && *filterVal != nil {
+		// Fields that are explicitly null are ignored by the filter,
+		// like the ones that aren't provided.
+		filterJson, err := json.Marshal(*filterVal)
+		if err != nil {
+			return nil, invalidArgument(ArgKey{Path: path, Name: args.Filter}, "%w", err)
+		}
+		mapFilter, err := filter.NewMapFilterForE(vals.Type().Elem(), string(filterJson))
+		if err != nil {
+			return nil, invalidArgument(ArgKey{Path: path, Name: args.Filter}, "%w", err)
+		}
+		items, err = filter.FilterAllE(mapFilter, items)
+		if err != nil {
+			return nil, invalidArgument(ArgKey{Path: path, Name: args.Filter}, "%w", err)
+		}
+	}
+	if orderBy != nil {
+		items = slices.Collect(filter.SortFor(slices.Values(items), *orderBy...))
+	}
+	if offset != nil || limit != nil {
+		items = slices.Collect(filter.Paginate(slices.Values(items), deref(offset), deref(limit)))
+	}
+
+	result := reflect.MakeSlice(vals.Type(), len(items), len(items))
+	for i, item := range items {
+		if item != nil {
+			result.Index(i).Set(reflect.ValueOf(item))
+		}
+	}
+
+	return result.Interface(), nil
+}
+
+// optionalArgValue is the same as GetArgValue, except that
+// an argument that isn't provided has a nil value.
+func optionalArgValue[T any](ctx context.Context, key ArgKey) (*T, error) {
+	if key.Name == "" {
+		return nil, nil
+	}
+	val, err := GetArgValue[T](ctx, key)
+	if errors.Is(err, ErrArgumentNotFound) {
+		return nil, nil
+	}
+
+	return val, err
+}
+
+// validateFilterArgs checks the ordering and pagination arguments,
+// which gqlgen only validates against their GraphQL types; the fields
+// of the ordering are checked against the type of the elements of the
+// result (see filter.ValidateOrderBy).
+func validateFilterArgs(path string, args FilterArgs, elemType reflect.Type, orderBy *[]filter.OrderBy, offset, limit *int) error {
+	if orderBy != nil {
+		err := filter.ValidateOrderBy(elemType, *orderBy...)
+		if err != nil {
+			return invalidArgument(ArgKey{Path: path, Name: args.OrderBy}, "%w", err)
+		}
+	}
+	if offset != nil && *offset < 0 {
+		return invalidArgument(ArgKey{Path: path, Name: args.Offset}, "the offset can't be negative")
+	}
+	if limit != nil && *limit < 0 {
+		return invalidArgument(ArgKey{Path: path, Name: args.Limit}, "the limit can't be negative")
+	}
+
+	return nil
+}
+
+// invalidArgument returns an *ArgumentError for an
+// invalid value of the argument with the given key.
+func invalidArgument(key ArgKey, format string, args ...any) error {
+	args = append([]any{errorz.ErrInvalidArgument, key}, args...)
+	return &ArgumentError{Key: key, Err: fmt.Errorf("%w '%s': "+format, args...)}
+}
+
+func deref[T any](val *T) T {
+	var zero T
+	if val == nil {
+		return zero
+	}
+	return *val
+}
@@ -0,0 +1,98 @@
+package gqlgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/errorz"
+)
+
+type Film struct {
+	Title  string `json:"title"`
+	Year   int    `json:"year"`
+	Studio string `json:"studio" filter:"eq,in"`
+}
+
+var testFilms = []*Film{
+	{Title: "Back to the Future", Year: 1985},
+	{Title: "Back to the Future Part II", Year: 1989},
+	{Title: "Who Framed Roger Rabbit", Year: 1988},
+	{Title: "Romancing the Stone", Year: 1984},
+}
+
+func resolveFilms(ctx context.Context) (any, error) {
+	return testFilms, nil
+}
+
+func TestFilterMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		expected []*Film
+	}{
+		{"filter", `{ films(filter: {year: {gte: 1985}}) { title } }`, []*Film{testFilms[0], testFilms[1], testFilms[2]}},
+		{"order and limit", `{ films(filter: {title: {startsWith: "Back", eq: null}}, orderBy: [{field: "year", direction: DESC}], limit: 1) { title } }`, []*Film{testFilms[1]}},
+		{"single order", `{ films(orderBy: {field: "title"}, offset: 2) { title } }`, []*Film{testFilms[3], testFilms[2]}},
+		{"or", `{ films(filter: {or: [{title: {contains: "Rabbit"}}, {year: {lt: 1985}}]}) { title } }`, []*Film{testFilms[2], testFilms[3]}},
+		{"or with a sibling", `{ films(filter: {or: [{title: {contains: "Rabbit"}}, {title: {startsWith: "Romancing"}}], year: {gte: 1985}}) { title } }`, []*Film{testFilms[2]}},
+		{"default limit", `{ films { title } }`, testFilms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(t, tt.query, nil)
+
+			result, err := FilterMiddleware(ctx, resolveFilms)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestFilterMiddleware_NotFilterable(t *testing.T) {
+	ctx := newTestContext(t, `{ movies(limit: 1) { title } }`, nil)
+
+	result, err := FilterMiddleware(ctx, resolveFilms)
+
+	require.NoError(t, err)
+	assert.Equal(t, testFilms, result)
+}
+
+func TestFilterable_Variables(t *testing.T) {
+	query := `query($filter: FilmFilter) { films(filter: $filter) { title } }`
+	ctx := newTestContext(t, query, map[string]any{"filter": map[string]any{"not": map[string]any{"title": map[string]any{"contains": "Back"}}}})
+
+	result, err := Filterable(ctx, nil, resolveFilms)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*Film{testFilms[2], testFilms[3]}, result)
+}
+
+func TestFilterable_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		resolver func(ctx context.Context) (any, error)
+		expected error
+	}{
+		{"invalid pattern", `{ films(filter: {title: {matches: "(Back"}}) { title } }`, resolveFilms, errorz.ErrInvalidArgument},
+		{"operator not allowed", `{ films(filter: {studio: {contains: "Amblin"}}) { title } }`, resolveFilms, errorz.ErrInvalidArgument},
+		{"unknown order field", `{ films(orderBy: [{field: "year"}, {field: "rating"}]) { title } }`, resolveFilms, errorz.ErrInvalidArgument},
+		{"negative offset", `{ films(offset: -1) { title } }`, resolveFilms, errorz.ErrInvalidArgument},
+		{"not a slice", `{ films { title } }`, func(ctx context.Context) (any, error) { return testFilms[0], nil }, errorz.ErrInvalidType},
+		{"resolver error", `{ films { title } }`, func(ctx context.Context) (any, error) { return nil, errorz.ErrNotFound }, errorz.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newTestContext(t, tt.query, nil)
+
+			_, err := Filterable(ctx, nil, tt.resolver)
+
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+	_, err := ApplyFilterArgs(context.Background(), testFilms)
+	assert.ErrorIs(t, err, ErrFieldNotFound)
+}
@@ -10,5 +10,30 @@ package gqlgen
 // about the query, such as the arguments that were
 // passed to sub-queries (that aren't available without
 // a separate resolver).
+//
+// GetArgValue decodes the arguments of any field of the query into
+// Go types, resolving variables and applying the default values that
+// are declared in the schema. Defaults of input-object fields nested
+// in variables are only known with the schema of the server, which
+// SchemaExtension adds to the context:
+//
+//	srv.Use(&gqlgen.SchemaExtension{})
+//
+// GetSelectedFields, GetSelectedPaths and IsSelected tell a resolver
+// which fields the client selected, so that it can avoid fetching
+// the data of the fields that weren't requested.
+//
+// The Filterable directive (or FilterMiddleware) applies the filter,
+// orderBy, offset and limit arguments of a list field to the result
+// of its resolver, using the filters of package filter.
+//
+// CostAnalyzer computes the depth, the number of fields and the weighted
+// cost of an operation, and the CostLimit handler extension rejects the
+// operations that exceed its limits before they are executed.
+//
+// ErrorPresenter gives GraphQL errors a code in their extensions, by the
+// errorz sentinel that they wrap, along with the argument they are about,
+// and Recover turns the panics of resolvers into sanitized errors.
+//
 // For more information about the gqlgen Go GraphQL generator,
 // see https://gqlgen.com
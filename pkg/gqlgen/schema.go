@@ -0,0 +1,53 @@
+package gqlgen
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+type schemaKey struct{}
+
+// WithSchema returns a copy of the context that carries the given
+// schema, so that GetArgValue can apply the default values of the
+// fields of any input object, including the ones that are nested in
+// the values of variables. See SchemaExtension, which does this for
+// every operation of a gqlgen server.
+func WithSchema(ctx context.Context, schema *ast.Schema) context.Context {
+	return context.WithValue(ctx, schemaKey{}, schema)
+}
+
+// GetSchema returns the schema that was added to the
+// context by WithSchema, or nil if there isn't one.
+func GetSchema(ctx context.Context) *ast.Schema {
+	schema, _ := ctx.Value(schemaKey{}).(*ast.Schema)
+	return schema
+}
+
+// SchemaExtension is a gqlgen handler extension that adds the schema
+// of the server to the context of every operation (see WithSchema):
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.Use(&gqlgen.SchemaExtension{})
+type SchemaExtension struct {
+	schema *ast.Schema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = &SchemaExtension{}
+
+func (e *SchemaExtension) ExtensionName() string {
+	return "Schema"
+}
+
+func (e *SchemaExtension) Validate(schema graphql.ExecutableSchema) error {
+	e.schema = schema.Schema()
+	return nil
+}
+
+func (e *SchemaExtension) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	return next(WithSchema(ctx, e.schema))
+}
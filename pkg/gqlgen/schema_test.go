@@ -0,0 +1,121 @@
+package gqlgen
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+	"github.com/stretchr/testify/require"
+	"github.com/tartale/go/pkg/filter"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const testSchema = `
+directive @cost(weight: Int!) on FIELD_DEFINITION
+
+scalar Date
+
+enum Kind {
+	MOVIE
+	SERIES
+}
+
+input DateRange {
+	from: Date
+	to: Date = "2100-01-01"
+}
+
+input MovieFilter {
+	title: String
+	kinds: [Kind!] = [MOVIE]
+	released: DateRange
+}
+
+type Person {
+	name: String!
+	movies(limit: Int = 10): [Movie!]!
+}
+
+type Movie {
+	title: String!
+	cast(first: Int): [Person!]!
+}
+
+type Query {
+	movies(filter: MovieFilter, limit: Int = 25, titles: [String!]): [Movie!]! @cost(weight: 2)
+	person(name: String!): Person
+}
+`
+
+// testFilterSchema declares a filterable field, with the input
+// types of pkg/filter for the Film type of filterable_test.go.
+var testFilterSchema = `
+directive @filterable on FIELD_DEFINITION
+
+enum Direction {
+	ASC
+	DESC
+}
+
+input OrderBy {
+	field: String!
+	direction: Direction
+}
+
+type Film {
+	title: String!
+	year: Int!
+}
+
+extend type Query {
+	films(filter: FilmFilter, orderBy: [OrderBy!], offset: Int, limit: Int = 10): [Film!]! @filterable
+}
+` + filter.GenerateGraphQLOperator() + filter.GenerateGraphQL[Film]()
+
+var testExecutableSchema = &graphql.ExecutableSchemaMock{
+	SchemaFunc: sync.OnceValue(func() *ast.Schema {
+		return gqlparser.MustLoadSchema(
+			&ast.Source{Name: "test.graphql", Input: testSchema},
+			&ast.Source{Name: "filter.graphql", Input: testFilterSchema},
+		)
+	}),
+	ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]any) (int, bool) {
+		return 0, false
+	},
+}
+
+// newTestContext returns the context that gqlgen would pass to the
+// resolver of the first root field of the given query, which is
+// validated against the test schema.
+func newTestContext(t *testing.T, query string, variables map[string]any) context.Context {
+	t.Helper()
+	ctx := graphql.StartOperationTrace(context.Background())
+	octx, errs := executor.New(testExecutableSchema).CreateOperationContext(ctx, &graphql.RawParams{
+		Query:     query,
+		Variables: variables,
+	})
+	require.Empty(t, errs)
+	ctx = graphql.WithOperationContext(ctx, octx)
+
+	fields := graphql.CollectFields(octx, octx.Operation.SelectionSet, nil)
+	require.NotEmpty(t, fields)
+
+	return graphql.WithFieldContext(ctx, newTestFieldContext(octx, nil, fields[0]))
+}
+
+func newTestFieldContext(octx *graphql.OperationContext, parent *graphql.FieldContext, field graphql.CollectedField) *graphql.FieldContext {
+	fctx := &graphql.FieldContext{
+		Parent: parent,
+		Object: field.ObjectDefinition.Name,
+		Field:  field,
+		Args:   field.ArgumentMap(octx.Variables),
+	}
+	fctx.Child = func(ctx context.Context, child graphql.CollectedField) (*graphql.FieldContext, error) {
+		return newTestFieldContext(octx, fctx, child), nil
+	}
+
+	return fctx
+}
@@ -0,0 +1,134 @@
+package gqlgen
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// SelectedField is a field that was selected in a GraphQL query,
+// along with the subfields that were selected within it.
+type SelectedField struct {
+	// Name is the name of the field in the schema.
+	Name string
+	// Aliases are the names of the field in the response, which
+	// are the same as the name unless the field was aliased; a field
+	// can be selected more than once, with different aliases.
+	Aliases []string
+	// Fields are the subfields that were selected within
+	// the field, under any of its aliases.
+	Fields []SelectedField
+}
+
+// Field returns the subfield with the given name,
+// or nil if it wasn't selected.
+func (f SelectedField) Field(name string) *SelectedField {
+	i := slices.IndexFunc(f.Fields, func(sf SelectedField) bool {
+		return sf.Name == name
+	})
+	if i < 0 {
+		return nil
+	}
+	return &f.Fields[i]
+}
+
+// GetSelectedFields returns the tree of the fields that were selected
+// in the operation of the gqlgen context, starting from its root fields.
+// Fragments and inline fragments are resolved, fields that are skipped
+// with the @skip and @include directives are left out, and the fields
+// that were selected under different aliases are merged, so that the
+// tree is keyed by the names of the fields in the schema. Nil is
+// returned if the context is not a gqlgen-provided context.
+func GetSelectedFields(ctx context.Context) []SelectedField {
+
+	if !graphql.HasOperationContext(ctx) {
+		return nil
+	}
+	octx := graphql.GetOperationContext(ctx)
+	if octx == nil || octx.Operation == nil {
+		return nil
+	}
+
+	return selectedFields(octx, octx.Operation.SelectionSet)
+}
+
+// GetSelectedPaths returns the paths of all the fields that were selected
+// in the operation of the gqlgen context (see GetSelectedFields), in the
+// same dotted form as the path of an ArgKey; for example:
+//
+//	{ movies { title cast { name } } }
+//
+// selects "movies", "movies.title", "movies.cast" and "movies.cast.name".
+func GetSelectedPaths(ctx context.Context) []string {
+
+	var paths []string
+	var addPaths func(prefix string, fields []SelectedField)
+	addPaths = func(prefix string, fields []SelectedField) {
+		for _, field := range fields {
+			path := prefix + field.Name
+			paths = append(paths, path)
+			addPaths(path+".", field.Fields)
+		}
+	}
+	addPaths("", GetSelectedFields(ctx))
+
+	return paths
+}
+
+// IsSelected reports whether the field at the given path was selected
+// in the operation of the gqlgen context (see GetSelectedPaths), so that
+// a resolver can skip the work of fetching fields that weren't requested:
+//
+//	if gqlgen.IsSelected(ctx, "movies.cast.name") {
+//		// join the cast of the movies
+//	}
+func IsSelected(ctx context.Context, path string) bool {
+
+	fields := GetSelectedFields(ctx)
+	var field *SelectedField
+	for _, name := range strings.Split(path, ".") {
+		field = SelectedField{Fields: fields}.Field(name)
+		if field == nil {
+			return false
+		}
+		fields = field.Fields
+	}
+
+	return field != nil
+}
+
+// selectedFields collects the fields of the given selection set,
+// merging the ones with the same name.
+func selectedFields(octx *graphql.OperationContext, selections ast.SelectionSet) []SelectedField {
+
+	var (
+		fields          []SelectedField
+		fieldSelections []ast.SelectionSet
+	)
+	for _, cf := range graphql.CollectFields(octx, selections, nil) {
+		i := slices.IndexFunc(fields, func(f SelectedField) bool {
+			return f.Name == cf.Name
+		})
+		if i < 0 {
+			i = len(fields)
+			fields = append(fields, SelectedField{Name: cf.Name})
+			fieldSelections = append(fieldSelections, nil)
+		}
+		alias := cf.Alias
+		if alias == "" {
+			alias = cf.Name
+		}
+		if !slices.Contains(fields[i].Aliases, alias) {
+			fields[i].Aliases = append(fields[i].Aliases, alias)
+		}
+		fieldSelections[i] = append(fieldSelections[i], cf.Selections...)
+	}
+	for i := range fields {
+		fields[i].Fields = selectedFields(octx, fieldSelections[i])
+	}
+
+	return fields
+}
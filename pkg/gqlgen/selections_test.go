@@ -0,0 +1,68 @@
+package gqlgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSelectionQuery = `
+query($withCast: Boolean!) {
+	films: movies(limit: 5) { title }
+	movies {
+		...movieFields
+		cast @include(if: $withCast) { name }
+	}
+	person(name: "Doc Brown") {
+		... on Person { movies { title } }
+	}
+}
+
+fragment movieFields on Movie {
+	title
+	cast { director: name }
+}
+`
+
+func TestGetSelectedFields(t *testing.T) {
+	ctx := newTestContext(t, testSelectionQuery, map[string]any{"withCast": false})
+
+	fields := GetSelectedFields(ctx)
+
+	require.Len(t, fields, 2)
+	movies := fields[0]
+	assert.Equal(t, "movies", movies.Name)
+	assert.Equal(t, []string{"films", "movies"}, movies.Aliases)
+	require.Len(t, movies.Fields, 2)
+	assert.Equal(t, "title", movies.Fields[0].Name)
+	cast := movies.Field("cast")
+	require.NotNil(t, cast)
+	assert.Equal(t, []string{"director"}, cast.Field("name").Aliases)
+	assert.Nil(t, movies.Field("director"))
+}
+
+func TestGetSelectedPaths(t *testing.T) {
+	ctx := newTestContext(t, testSelectionQuery, map[string]any{"withCast": true})
+
+	paths := GetSelectedPaths(ctx)
+
+	expected := []string{
+		"movies", "movies.title", "movies.cast", "movies.cast.name",
+		"person", "person.movies", "person.movies.title",
+	}
+	assert.Equal(t, expected, paths)
+	assert.Empty(t, GetSelectedPaths(context.Background()))
+}
+
+func TestIsSelected(t *testing.T) {
+	ctx := newTestContext(t, testSelectionQuery, map[string]any{"withCast": true})
+
+	assert.True(t, IsSelected(ctx, "movies.cast.name"))
+	assert.True(t, IsSelected(ctx, "person.movies"))
+	assert.False(t, IsSelected(ctx, "films"))
+	assert.False(t, IsSelected(ctx, "movies.cast.movies"))
+	assert.False(t, IsSelected(ctx, "person.name"))
+	assert.False(t, IsSelected(context.Background(), "movies"))
+}
@@ -0,0 +1,246 @@
+package gqlgen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/tartale/go/pkg/generics"
+	"github.com/tartale/go/pkg/jsontime"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// argValue returns the value of the argument of the given field with
+// the given name, in the same way as ast.Field.ArgumentMap: variables
+// are resolved from the operation context, and the default value of
+// the argument (or of its variable) is used if it is not provided.
+// The defaults of the fields of input objects are then applied. The
+// result is false if the argument has no value.
+func argValue(ctx context.Context, field *ast.Field, name string) (any, bool, error) {
+	var vars map[string]any
+	if graphql.HasOperationContext(ctx) {
+		vars = graphql.GetOperationContext(ctx).Variables
+	}
+	var argDef *ast.ArgumentDefinition
+	if field.Definition != nil {
+		argDef = field.Definition.Arguments.ForName(name)
+	}
+
+	var (
+		val any
+		ok  bool
+		err error
+	)
+	if arg := field.Arguments.ForName(name); arg != nil {
+		if arg.Value.Kind == ast.Variable {
+			val, ok = vars[arg.Value.Raw]
+			varDef := arg.Value.VariableDefinition
+			if !ok && varDef != nil && varDef.DefaultValue != nil {
+				val, err = varDef.DefaultValue.Value(vars)
+				ok = true
+			}
+		} else {
+			val, err = arg.Value.Value(vars)
+			ok = true
+		}
+	}
+	if !ok && argDef != nil && argDef.DefaultValue != nil {
+		val, err = argDef.DefaultValue.Value(vars)
+		ok = true
+	}
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	if argDef == nil {
+		return val, true, nil
+	}
+	types := typesOf(ctx, field)
+	val, err = withDefaults(val, argDef.Type, types)
+
+	return val, true, err
+}
+
+// withDefaults returns a copy of the given value of the given type,
+// in which the fields of input objects that are not provided are set
+// to their default values (if any). The definitions of the input
+// objects are looked up by name in the given map.
+func withDefaults(val any, typ *ast.Type, types map[string]*ast.Definition) (any, error) {
+	if val == nil || typ == nil {
+		return val, nil
+	}
+	if typ.Elem != nil {
+		list, ok := val.([]any)
+		if !ok {
+			// A single value is coerced into a list of one element.
+			list = []any{val}
+		}
+		result := make([]any, len(list))
+		for i, elem := range list {
+			var err error
+			result[i], err = withDefaults(elem, typ.Elem, types)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+	def := types[typ.Name()]
+	object, ok := val.(map[string]any)
+	if def == nil || def.Kind != ast.InputObject || !ok {
+		return val, nil
+	}
+	result := maps.Clone(object)
+	for _, fieldDef := range def.Fields {
+		fieldVal, ok := result[fieldDef.Name]
+		if !ok && fieldDef.DefaultValue != nil {
+			var err error
+			fieldVal, err = fieldDef.DefaultValue.Value(nil)
+			if err != nil {
+				return nil, err
+			}
+			ok = true
+		}
+		if !ok {
+			continue
+		}
+		fieldVal, err := withDefaults(fieldVal, fieldDef.Type, types)
+		if err != nil {
+			return nil, err
+		}
+		result[fieldDef.Name] = fieldVal
+	}
+
+	return result, nil
+}
+
+// typesOf returns the definitions of the types that the arguments
+// of the given field can refer to. These are the types of the schema
+// in the context, if any (see WithSchema); otherwise, they are the
+// types that the validation of the query has attached to the values
+// of the arguments of the field, and to the variables of the operation,
+// which include the input objects that are written in the query, but
+// not the ones that are nested in the values of variables.
+func typesOf(ctx context.Context, field *ast.Field) map[string]*ast.Definition {
+	if schema := GetSchema(ctx); schema != nil {
+		return schema.Types
+	}
+	types := map[string]*ast.Definition{}
+	var addValue func(*ast.Value)
+	addValue = func(v *ast.Value) {
+		if v == nil {
+			return
+		}
+		if v.Definition != nil {
+			types[v.Definition.Name] = v.Definition
+		}
+		for _, child := range v.Children {
+			addValue(child.Value)
+		}
+	}
+	for _, arg := range field.Arguments {
+		addValue(arg.Value)
+	}
+	if graphql.HasOperationContext(ctx) {
+		if op := graphql.GetOperationContext(ctx).Operation; op != nil {
+			for _, varDef := range op.VariableDefinitions {
+				if varDef.Definition != nil {
+					types[varDef.Definition.Name] = varDef.Definition
+				}
+			}
+		}
+	}
+
+	return types
+}
+
+// decodeValue decodes the value of an argument into the given type.
+// Values of other types than T are round-tripped through JSON, so that
+// input objects are decoded with the json tags (and the jsontime.Time
+// fields) of T; if that fails, the value is cast into T, e.g. for a
+// number that is passed to a string argument. A date that can't be
+// parsed with the format of its field is an error.
+func decodeValue[T any](val any) (*T, error) {
+	if val == nil {
+		return nil, nil
+	}
+	if result, ok := val.(T); ok {
+		return &result, nil
+	}
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	var result T
+	err = json.Unmarshal(data, &result)
+	if err == nil {
+		if err := unmarshalTime(reflect.ValueOf(&result).Elem()); err != nil {
+			return nil, err
+		}
+		return &result, nil
+	}
+
+	return generics.CastTo[T](val)
+}
+
+var typeOfJSONTime = reflect.TypeFor[jsontime.Time]()
+
+// unmarshalTime parses the jsontime.Time fields (or pointers to them)
+// of the given value, which can be a struct, or a pointer or a list of
+// them, with their format tags (or jsontime.DefaultFormat), as with
+// jsontime.Parse. The fields that have no value (e.g. an optional date
+// that is omitted) are left as zero times (or nil pointers).
+func unmarshalTime(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return unmarshalTime(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := unmarshalTime(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			var t *jsontime.Time
+			switch fieldVal := v.Field(i); fieldVal.Type() {
+			case typeOfJSONTime:
+				t = fieldVal.Addr().Interface().(*jsontime.Time)
+			case reflect.PointerTo(typeOfJSONTime):
+				t, _ = fieldVal.Interface().(*jsontime.Time)
+			default:
+				if err := unmarshalTime(fieldVal); err != nil {
+					return err
+				}
+				continue
+			}
+			if t == nil {
+				continue
+			}
+			raw := strings.Trim(t.Raw, `"`)
+			if raw == "" {
+				continue
+			}
+			parsed, err := jsontime.Parse(raw, field.Tag.Get("format"))
+			if err != nil {
+				name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+				if name == "" {
+					name = field.Name
+				}
+				return fmt.Errorf("invalid date '%s' of field '%s': %w", raw, name, err)
+			}
+			*t = *parsed
+		}
+	}
+
+	return nil
+}
@@ -47,6 +47,29 @@ func New(t time.Time) *Time {
 	return &Time{Time: t}
 }
 
+// Parse parses the string representation of a time, which can be
+// quoted (as in a JSON document), with the given format, or with
+// DefaultFormat if the format is empty; the unquoted string is kept
+// as the Raw value of the result.
+//
+// Example:
+//
+//	jsTime, err := jsontime.Parse(`"2024-01-02"`, "2006-01-02")
+//	_ = jsTime
+//	_ = err
+func Parse(raw, format string) (*Time, error) {
+	if format == "" {
+		format = DefaultFormat
+	}
+	unquoted := strings.Trim(raw, `"`)
+	t, err := time.Parse(format, unquoted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Time{Time: t, Raw: unquoted}, nil
+}
+
 // Now returns a jsontime.Time set to the current instant.
 func Now() *Time {
 	return &Time{Time: time.Now()}
@@ -126,15 +149,13 @@ func UnmarshalTime(v any) {
 	walkFn := func(field reflect.StructField, value reflect.Value) error {
 		switch val := value.Interface().(type) {
 		case Time:
-			format := getFormat(field)
-			unquoted := strings.Trim(val.Raw, `"`)
-			newTime, err := time.Parse(format, unquoted)
+			parsed, err := Parse(val.Raw, getFormat(field))
 			if err != nil {
 				return err
 			}
 			t := value.FieldByName("Time")
-			t.Set(reflect.ValueOf(newTime))
-			logz.Logger().Debugf("unmarshaled json time field; name: %s, newTime: %s\n", field.Name, newTime)
+			t.Set(reflect.ValueOf(parsed.Time))
+			logz.Logger().Debugf("unmarshaled json time field; name: %s, newTime: %s\n", field.Name, parsed.Time)
 		}
 
 		return nil
@@ -209,3 +209,13 @@ func TestJSONTime_JSONUnmarshal_NestedAnyField(t *testing.T) {
 	assert.Equal(t, time.July, testStruct.Bar.Month())
 	assert.Equal(t, 31, testStruct.Bar.Day())
 }
+
+func TestParse(t *testing.T) {
+	parsed, err := Parse(`"07/31/1976"`, "01/02/2006")
+
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(1976, 7, 31, 0, 0, 0, 0, time.UTC), parsed.Time)
+	assert.Equal(t, "07/31/1976", parsed.Raw)
+	_, err = Parse("1976-07-31", "")
+	assert.Error(t, err)
+}